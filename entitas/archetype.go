@@ -0,0 +1,482 @@
+package entitas
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// chunkCapacity 是每个chunk最多存放的entity数量.
+const chunkCapacity = 128
+
+// --- Archetype --------------------------------------------------------------
+
+// archetype 存放组件集合完全相同的所有entity.
+// 组件按列存放在固定大小的chunk里, 列里连续存放的是Component接口值, 组件本身还是各自分配在堆上的,
+// 所以这不是按组件类型连续存放数据的存储. group也不按chunk遍历, 见NewArchetypeContext.
+type archetype struct {
+	types   []ComponentType // 排好序的组件类型
+	columns []int           // ComponentType -> 列下标, 不存在时为-1
+	chunks  []*chunk
+	add     map[ComponentType]*archetype // 添加一个组件后到达的archetype
+	remove  map[ComponentType]*archetype // 删除一个组件后到达的archetype
+}
+
+type chunk struct {
+	entities []*archetypeEntity
+	columns  [][]Component
+}
+
+func newArchetype(types []ComponentType) *archetype {
+	a := &archetype{
+		types:  types,
+		add:    make(map[ComponentType]*archetype),
+		remove: make(map[ComponentType]*archetype),
+	}
+	if len(types) > 0 {
+		a.columns = make([]int, int(types[len(types)-1])+1)
+		for i := range a.columns {
+			a.columns[i] = -1
+		}
+		for i, t := range types {
+			a.columns[t] = i
+		}
+	}
+	return a
+}
+
+func (a *archetype) column(t ComponentType) int {
+	if int(t) < len(a.columns) {
+		return a.columns[t]
+	}
+	return -1
+}
+
+func (a *archetype) count() int {
+	if len(a.chunks) == 0 {
+		return 0
+	}
+	return (len(a.chunks)-1)*chunkCapacity + len(a.chunks[len(a.chunks)-1].entities)
+}
+
+// push 在最后一个chunk的末尾给entity分配一行, 组件数据由调用者填写.
+func (a *archetype) push(e *archetypeEntity) (*chunk, int) {
+	var c *chunk
+	if n := len(a.chunks); n > 0 && len(a.chunks[n-1].entities) < chunkCapacity {
+		c = a.chunks[n-1]
+	} else {
+		c = &chunk{
+			entities: make([]*archetypeEntity, 0, chunkCapacity),
+			columns:  make([][]Component, len(a.types)),
+		}
+		for i := range c.columns {
+			c.columns[i] = make([]Component, 0, chunkCapacity)
+		}
+		a.chunks = append(a.chunks, c)
+	}
+	c.entities = append(c.entities, e)
+	for i := range c.columns {
+		c.columns[i] = append(c.columns[i], nil)
+	}
+	return c, len(c.entities) - 1
+}
+
+// removeRow 删除一行, 用最后一个entity填补空位, 保证chunk始终是紧凑的.
+func (a *archetype) removeRow(c *chunk, row int) {
+	last := a.chunks[len(a.chunks)-1]
+	lastRow := len(last.entities) - 1
+	if c != last || row != lastRow {
+		moved := last.entities[lastRow]
+		c.entities[row] = moved
+		for i := range c.columns {
+			c.columns[i][row] = last.columns[i][lastRow]
+		}
+		moved.chunk, moved.row = c, row
+	}
+	last.entities[lastRow] = nil
+	last.entities = last.entities[:lastRow]
+	for i := range last.columns {
+		last.columns[i][lastRow] = nil
+		last.columns[i] = last.columns[i][:lastRow]
+	}
+	if lastRow == 0 {
+		a.chunks[len(a.chunks)-1] = nil
+		a.chunks = a.chunks[:len(a.chunks)-1]
+	}
+}
+
+func (a *archetype) String() string {
+	return fmt.Sprintf("Archetype(%v)", a.types)
+}
+
+// --- Store ------------------------------------------------------------------
+
+type archetypeStore struct {
+	root       *archetype
+	archetypes map[string]*archetype
+}
+
+func newArchetypeStore() *archetypeStore {
+	root := newArchetype(nil)
+	return &archetypeStore{
+		root:       root,
+		archetypes: map[string]*archetype{"": root},
+	}
+}
+
+func (s *archetypeStore) archetype(types []ComponentType) *archetype {
+	key := archetypeKey(types)
+	if a, ok := s.archetypes[key]; ok {
+		return a
+	}
+	a := newArchetype(types)
+	s.archetypes[key] = a
+	return a
+}
+
+func (s *archetypeStore) withType(a *archetype, t ComponentType) *archetype {
+	if next, ok := a.add[t]; ok {
+		return next
+	}
+	types := make([]ComponentType, len(a.types), len(a.types)+1)
+	copy(types, a.types)
+	types = append(types, t)
+	sort.Sort(TypesByType(types))
+	next := s.archetype(types)
+	a.add[t] = next
+	next.remove[t] = a
+	return next
+}
+
+func (s *archetypeStore) withoutType(a *archetype, t ComponentType) *archetype {
+	if next, ok := a.remove[t]; ok {
+		return next
+	}
+	types := make([]ComponentType, 0, len(a.types))
+	for _, at := range a.types {
+		if at != t {
+			types = append(types, at)
+		}
+	}
+	next := s.archetype(types)
+	a.remove[t] = next
+	next.add[t] = a
+	return next
+}
+
+// move 把entity搬到另一个archetype, 两边都有的组件会被复制过去.
+// 没有组件的entity不占用任何chunk.
+func (s *archetypeStore) move(e *archetypeEntity, to *archetype) {
+	from, c, row := e.arch, e.chunk, e.row
+	var nc *chunk
+	nrow := -1
+	if to != s.root {
+		nc, nrow = to.push(e)
+		if from != s.root {
+			for i, t := range to.types {
+				if col := from.column(t); col >= 0 {
+					nc.columns[i][nrow] = c.columns[col][row]
+				}
+			}
+		}
+	}
+	if from != s.root {
+		from.removeRow(c, row)
+	}
+	e.arch, e.chunk, e.row = to, nc, nrow
+}
+
+func archetypeKey(types []ComponentType) string {
+	var b strings.Builder
+	for _, t := range types {
+		b.WriteByte(byte(t >> 8))
+		b.WriteByte(byte(t))
+	}
+	return b.String()
+}
+
+// --- Entity -----------------------------------------------------------------
+
+type archetypeEntity struct {
+	entityLock
+	entityGeneration
+	entityValidator
+	componentVersions
+	id               EntityID
+	store            *archetypeStore
+	arch             *archetype
+	chunk            *chunk
+	row              int
+	callbacks        map[ComponentEvent]callbackList[ComponentCallback]
+	replaceCallbacks callbackList[ComponentReplacedCallback]
+}
+
+func newArchetypeEntity(id int, store *archetypeStore) *archetypeEntity {
+	return &archetypeEntity{
+		id:        EntityID(id),
+		store:     store,
+		arch:      store.root,
+		row:       -1,
+		callbacks: make(map[ComponentEvent]callbackList[ComponentCallback]),
+	}
+}
+
+func (e *archetypeEntity) AddComponent(cs ...Component) error {
+	e.lock()
+	defer e.unlock()
+	return e.addComponent(cs...)
+}
+
+func (e *archetypeEntity) addComponent(cs ...Component) error {
+	for _, c := range cs {
+		if e.HasComponent(c.Type()) {
+			return ErrComponentExists
+		}
+		if err := e.validate(e, c); err != nil {
+			return err
+		}
+		e.store.move(e, e.store.withType(e.arch, c.Type()))
+		e.set(c)
+		e.callback(ComponentAdded, c)
+	}
+	return nil
+}
+
+// RebuildComponentIndex 对archetype存储没有意义, 组件本来就是按类型排好的.
+func (e *archetypeEntity) RebuildComponentIndex() {}
+
+func (e *archetypeEntity) ReplaceComponent(cs ...Component) error {
+	e.lock()
+	defer e.unlock()
+	return e.replaceComponent(cs...)
+}
+
+func (e *archetypeEntity) replaceComponent(cs ...Component) error {
+	for _, c := range cs {
+		if err := e.validate(e, c); err != nil {
+			return err
+		}
+		if prev := e.GetComponent(c.Type()); prev != nil {
+			e.set(c)
+			e.callback(ComponentReplaced, c)
+			e.replaceCallback(prev, c)
+		} else {
+			e.store.move(e, e.store.withType(e.arch, c.Type()))
+			e.set(c)
+			e.callback(ComponentAdded, c)
+		}
+	}
+	return nil
+}
+
+func (e *archetypeEntity) WillRemoveComponent(ts ...ComponentType) error {
+	e.lock()
+	defer e.unlock()
+	for _, t := range ts {
+		c, err := e.Component(t)
+		if err != nil {
+			return err
+		}
+		e.callback(ComponentWillBeRemoved, c)
+	}
+	return nil
+}
+
+func (e *archetypeEntity) RemoveComponent(ts ...ComponentType) error {
+	e.lock()
+	defer e.unlock()
+	return e.removeComponent(ts...)
+}
+
+func (e *archetypeEntity) removeComponent(ts ...ComponentType) error {
+	for _, t := range ts {
+		c, err := e.Component(t)
+		if err != nil {
+			return err
+		}
+		e.callback(ComponentWillBeRemoved, c)
+		e.store.move(e, e.store.withoutType(e.arch, t))
+		e.forget(t)
+		e.callback(ComponentRemoved, c)
+	}
+	return nil
+}
+
+func (e *archetypeEntity) RemoveAllComponents() {
+	e.lock()
+	defer e.unlock()
+	e.removeAllComponents()
+}
+
+func (e *archetypeEntity) removeAllComponents() {
+	components := e.Components()
+
+	for _, c := range components {
+		e.callback(ComponentWillBeRemoved, c)
+	}
+
+	e.store.move(e, e.store.root)
+	e.forgetAll()
+
+	for _, c := range components {
+		e.callback(ComponentRemoved, c)
+	}
+}
+
+func (e *archetypeEntity) MarkChanged(ts ...ComponentType) {
+	e.lock()
+	defer e.unlock()
+	for _, t := range ts {
+		if e.HasComponent(t) {
+			e.touch(t)
+		}
+	}
+}
+
+func (e *archetypeEntity) ID() EntityID {
+	return e.id
+}
+
+func (e *archetypeEntity) AddCallback(ev ComponentEvent, cb ComponentCallback) Subscription {
+	e.lock()
+	defer e.unlock()
+	return e.addCallback(ev, e.deferred(cb))
+}
+
+func (e *archetypeEntity) addCallback(ev ComponentEvent, cb ComponentCallback) Subscription {
+	cbs := e.callbacks[ev]
+	sub := cbs.add(cb)
+	e.callbacks[ev] = cbs
+	return sub
+}
+
+func (e *archetypeEntity) AddReplaceCallback(cb ComponentReplacedCallback) Subscription {
+	e.lock()
+	defer e.unlock()
+	return e.addReplaceCallback(e.deferredReplace(cb))
+}
+
+func (e *archetypeEntity) addReplaceCallback(cb ComponentReplacedCallback) Subscription {
+	return e.replaceCallbacks.add(cb)
+}
+
+func (e *archetypeEntity) RemoveCallback(sub Subscription) bool {
+	e.lock()
+	defer e.unlock()
+	for ev, cbs := range e.callbacks {
+		if cbs.remove(sub) {
+			if len(cbs) == 0 {
+				delete(e.callbacks, ev)
+			} else {
+				e.callbacks[ev] = cbs
+			}
+			return true
+		}
+	}
+	return e.replaceCallbacks.remove(sub)
+}
+
+func (e *archetypeEntity) HasCallbacks() bool {
+	return len(e.callbacks) > 0 || len(e.replaceCallbacks) > 0
+}
+
+func (e *archetypeEntity) RemoveAllCallbacks() {
+	e.lock()
+	defer e.unlock()
+	e.removeAllCallbacks()
+}
+
+func (e *archetypeEntity) removeAllCallbacks() {
+	e.callbacks = make(map[ComponentEvent]callbackList[ComponentCallback])
+	e.replaceCallbacks = nil
+}
+
+func (e *archetypeEntity) HasComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if e.arch.column(t) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *archetypeEntity) HasAnyComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if e.arch.column(t) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *archetypeEntity) Component(t ComponentType) (Component, error) {
+	c := e.GetComponent(t)
+	if c == nil {
+		return nil, ErrComponentDoesNotExist
+	}
+	return c, nil
+}
+
+func (e *archetypeEntity) GetComponent(t ComponentType) Component {
+	col := e.arch.column(t)
+	if col < 0 {
+		return nil
+	}
+	return e.chunk.columns[col][e.row]
+}
+
+func (e *archetypeEntity) DictGetComponent(t ComponentType) Component {
+	return e.GetComponent(t)
+}
+
+func (e *archetypeEntity) BinarySearchComponent(t ComponentType) Component {
+	return e.GetComponent(t)
+}
+
+func (e *archetypeEntity) LinearSearchComponent(t ComponentType) Component {
+	return e.GetComponent(t)
+}
+
+func (e *archetypeEntity) Components() []Component {
+	components := make([]Component, len(e.arch.types))
+	for i := range e.arch.types {
+		components[i] = e.chunk.columns[i][e.row]
+	}
+	return components
+}
+
+// ForEachComponent 遍历调用时的archetype的类型, fn里增删组件之后entity会搬到别的chunk, 每次都重新按类型取组件.
+func (e *archetypeEntity) ForEachComponent(fn func(Component)) {
+	for _, t := range e.arch.types {
+		if c := e.GetComponent(t); c != nil {
+			fn(c)
+		}
+	}
+}
+
+func (e *archetypeEntity) ComponentIndices() []ComponentType {
+	types := make([]ComponentType, len(e.arch.types))
+	copy(types, e.arch.types)
+	return types
+}
+
+func (e *archetypeEntity) String() string {
+	return fmt.Sprintf("Entity_%d(%v)", e.id, e.Components())
+}
+
+func (e *archetypeEntity) set(c Component) {
+	e.chunk.columns[e.arch.column(c.Type())][e.row] = c
+	e.touch(c.Type())
+}
+
+func (e *archetypeEntity) callback(ev ComponentEvent, c Component) {
+	for _, cb := range e.callbacks[ev] {
+		cb.fn(e, c)
+	}
+}
+
+func (e *archetypeEntity) replaceCallback(prev, cur Component) {
+	for _, cb := range e.replaceCallbacks {
+		cb.fn(e, prev, cur)
+	}
+}
@@ -0,0 +1,112 @@
+package entitas
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestArchetypeContext(t *testing.T) {
+	Convey("Given a new archetype context", t, func() {
+		p := NewArchetypeContext(0)
+
+		Convey("It creates entities with components", func() {
+			e := p.CreateEntity(NewComponentA(1), NewComponentB(2))
+			So(e.HasComponent(ComponentA, ComponentB), ShouldBeTrue)
+			So(e.GetComponent(ComponentA).(*componentA).value, ShouldEqual, 1)
+			So(e.Components(), ShouldHaveLength, 2)
+			So(fmt.Sprintf("%v", e), ShouldEqual, "Entity_0([A B])")
+		})
+
+		Convey("It keeps component values when moving between archetypes", func() {
+			a := NewComponentA(1)
+			b := NewComponentB(2)
+			e := p.CreateEntity(a)
+			e.AddComponent(b, NewComponentC())
+			So(e.GetComponent(ComponentA), ShouldEqual, a)
+			So(e.GetComponent(ComponentB), ShouldEqual, b)
+
+			e.RemoveComponent(ComponentC)
+			So(e.HasComponent(ComponentC), ShouldBeFalse)
+			So(e.GetComponent(ComponentA), ShouldEqual, a)
+			So(e.GetComponent(ComponentB), ShouldEqual, b)
+		})
+
+		Convey("It returns an error when a component is already added", func() {
+			e := p.CreateEntity(NewComponentA(1))
+			So(e.AddComponent(NewComponentA(2)), ShouldEqual, ErrComponentExists)
+		})
+
+		Convey("It stores entities with the same components in the same chunks", func() {
+			entities := make([]Entity, chunkCapacity*2+1)
+			for i := range entities {
+				entities[i] = p.CreateEntity(NewComponentA(i), NewComponentB(float32(i)))
+			}
+			store := entities[0].(*archetypeEntity).store
+			arch := store.archetype([]ComponentType{ComponentA, ComponentB})
+			So(arch.chunks, ShouldHaveLength, 3)
+			So(arch.count(), ShouldEqual, len(entities))
+
+			Convey("It keeps chunks compact when entities leave", func() {
+				p.DestroyEntity(entities[0])
+				entities[1].RemoveComponent(ComponentB)
+				So(arch.count(), ShouldEqual, len(entities)-2)
+				So(arch.chunks, ShouldHaveLength, 2)
+				for i := 2; i < len(entities); i++ {
+					So(entities[i].GetComponent(ComponentA).(*componentA).value, ShouldEqual, i)
+				}
+				So(entities[1].GetComponent(ComponentA).(*componentA).value, ShouldEqual, 1)
+			})
+		})
+
+		Convey("It keeps groups up to date", func() {
+			g := p.Group(AllOf(ComponentA, ComponentB))
+			e1 := p.CreateEntity(NewComponentA(1), NewComponentB(1))
+			e2 := p.CreateEntity(NewComponentA(2))
+			So(g.Entities(), ShouldResemble, []Entity{e1})
+
+			e2.AddComponent(NewComponentB(2))
+			So(g.Entities(), ShouldContain, e2)
+
+			e1.RemoveComponent(ComponentB)
+			So(g.Entities(), ShouldNotContain, e1)
+
+			p.DestroyEntity(e2)
+			So(g.Entities(), ShouldBeEmpty)
+		})
+
+		Convey("It dispatches OnEntityWillBeRemoved when a component will be removed", func() {
+			g := p.Group(AllOf(ComponentA))
+			e := p.CreateEntity(NewComponentA(1))
+			willRemove := 0
+			g.AddCallback(EntityWillBeRemoved, func(g Group, e Entity) { willRemove++ })
+			e.WillRemoveComponent(ComponentA)
+			So(willRemove, ShouldEqual, 1)
+			So(e.HasComponent(ComponentA), ShouldBeTrue)
+		})
+
+		Convey("It reuses destroyed entities", func() {
+			e := p.CreateEntity(NewComponentA(1))
+			p.DestroyEntity(e)
+			So(e.Components(), ShouldBeEmpty)
+			So(p.CreateEntity(), ShouldEqual, e)
+		})
+	})
+}
+
+func BenchmarkArchetypeGroupGetComponent(b *testing.B) {
+	p := NewArchetypeContext(0)
+	for i := 0; i < 10000; i++ {
+		p.CreateEntity(NewComponentA(i), NewComponentB(float32(i)), NewComponentC())
+	}
+	g := p.Group(AllOf(ComponentA, ComponentB))
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, e := range g.Entities() {
+			e.GetComponent(ComponentA)
+			e.GetComponent(ComponentB)
+		}
+	}
+}
@@ -0,0 +1,659 @@
+package entitas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrBadSnapshot    = errors.New("bad snapshot")
+	ErrSchemaMismatch = errors.New("component schema mismatch")
+	ErrNoMigration    = errors.New("no migration for component version")
+)
+
+const (
+	binaryMagic   = "ECSB"
+	binaryVersion = 1
+	maxPayload    = 16 << 20 // 单个组件数据的长度上限
+	chunkPayload  = 64 << 10 // 超过这个长度的数据边读边分配, 损坏的长度最多分配和实际数据差不多大的内存
+)
+
+// ComponentCodec 负责一种组件的二进制编码.
+// Version是当前的数据格式版本, 会写进存档头里; 加载时Decode收到写存档时的版本,
+// 旧版本的数据需要在Decode里迁移成当前的组件.
+type ComponentCodec interface {
+	Version() uint32
+	Encode(c Component) ([]byte, error)
+	Decode(version uint32, data []byte) (Component, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[ComponentType]ComponentCodec)
+)
+
+// SetComponentCodec 设置组件类型t使用的编码, codec为nil时恢复默认.
+// 没有设置的组件使用版本为1的ReflectCodec.
+func SetComponentCodec(t ComponentType, codec ComponentCodec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if codec == nil {
+		delete(codecs, t)
+		return
+	}
+	codecs[t] = codec
+}
+
+func codecFor(info ComponentInfo) ComponentCodec {
+	codecsMu.RLock()
+	codec, ok := codecs[info.Type]
+	codecsMu.RUnlock()
+	if ok {
+		return codec
+	}
+	return &ReflectCodec{goType: info.GoType, version: 1}
+}
+
+// --- Snapshot ---------------------------------------------------------------
+
+// Snapshot 是Context在某一时刻的编码后的内容, 可以写成二进制存档, 也可以用Diff和另一个Snapshot比较.
+type Snapshot struct {
+	schemas  map[ComponentType]schema
+	entities []snapshotEntity // 按ID排序
+}
+
+// schema 是存档头里一种组件的信息.
+type schema struct {
+	name    string
+	version uint32
+	codec   ComponentCodec
+}
+
+type snapshotEntity struct {
+	id         EntityID
+	components []EncodedComponent // 按ComponentType排序
+}
+
+// EncodedComponent 是用ComponentCodec编码之后的组件.
+type EncodedComponent struct {
+	Type ComponentType
+	Data []byte
+}
+
+// TakeSnapshot 编码ctx里所有的entity. 组件必须在DefaultRegistry里注册过.
+func TakeSnapshot(ctx Context) (*Snapshot, error) {
+	s := &Snapshot{schemas: make(map[ComponentType]schema)}
+	for _, r := range capture(ctx) {
+		e := snapshotEntity{id: r.ID, components: make([]EncodedComponent, len(r.Components))}
+		for i, c := range r.Components {
+			sc, err := s.schema(c.Type())
+			if err != nil {
+				return nil, fmt.Errorf("%w on entity %d", err, r.ID)
+			}
+			data, err := sc.codec.Encode(c)
+			if err != nil {
+				return nil, fmt.Errorf("entitas: encode %s on entity %d: %w", sc.name, r.ID, err)
+			}
+			e.components[i] = EncodedComponent{Type: c.Type(), Data: data}
+		}
+		s.entities = append(s.entities, e)
+	}
+	return s, nil
+}
+
+func (s *Snapshot) schema(t ComponentType) (schema, error) {
+	if sc, ok := s.schemas[t]; ok {
+		return sc, nil
+	}
+	info, ok := DefaultRegistry.Info(t)
+	if !ok {
+		return schema{}, fmt.Errorf("%w: %d", ErrComponentNotRegistered, t)
+	}
+	codec := codecFor(info)
+	sc := schema{name: info.Name, version: codec.Version(), codec: codec}
+	s.schemas[t] = sc
+	return sc, nil
+}
+
+// decodeComponent 按存档头里记录的版本解码组件.
+func decodeComponent(schemas map[ComponentType]schema, c EncodedComponent) (Component, error) {
+	sc, ok := schemas[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: component %d is not in the header", ErrBadSnapshot, c.Type)
+	}
+	component, err := sc.codec.Decode(sc.version, c.Data)
+	if err != nil {
+		return nil, fmt.Errorf("entitas: decode %s: %w", sc.name, err)
+	}
+	return component, nil
+}
+
+// --- Save / Load ------------------------------------------------------------
+
+// SaveBinary 把ctx里所有的entity写成二进制存档. 格式是:
+//
+//	magic "ECSB", 格式版本
+//	组件类型数量, 每种类型的ComponentType, 注册的名字, 数据格式版本
+//	entity数量, 每个entity的ID, 组件数量, 每个组件的ComponentType, 数据长度, 数据
+//
+// 整数都是varint. 组件必须在DefaultRegistry里注册过.
+func SaveBinary(ctx Context, w io.Writer) error {
+	s, err := TakeSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	bw := newBinaryWriter(w)
+	bw.header(binaryMagic, s.schemas)
+	bw.uvarint(uint64(len(s.entities)))
+	for _, e := range s.entities {
+		bw.uvarint(uint64(e.id))
+		bw.components(e.components)
+	}
+	return bw.Flush()
+}
+
+// LoadBinary 读取SaveBinary写出的存档, 替换掉ctx里所有的entity. entity保留原来的ID.
+// 存档头里组件的名字必须和当前注册的名字一致. 存档有错误时ctx不会被修改,
+// entity没有通过唯一组件或者group的检查时ctx恢复成加载之前的样子, 见restore.
+func LoadBinary(ctx Context, r io.Reader) error {
+	br := newBinaryReader(r)
+	schemas, err := br.header(binaryMagic)
+	if err != nil {
+		return err
+	}
+	count := br.uvarint()
+	var entities []snapshotEntity
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		entities = append(entities, snapshotEntity{id: EntityID(br.uvarint()), components: br.components()})
+	}
+	if br.err != nil {
+		return br.err
+	}
+
+	records := make([]entityRecord, len(entities))
+	for i, e := range entities {
+		records[i].ID = e.id
+		for _, c := range e.components {
+			component, err := decodeComponent(schemas, c)
+			if err != nil {
+				return fmt.Errorf("%w on entity %d", err, e.id)
+			}
+			records[i].Components = append(records[i].Components, component)
+		}
+	}
+	return restore(ctx, records)
+}
+
+// --- Encoding ---------------------------------------------------------------
+
+type binaryWriter struct {
+	*bufio.Writer
+	buf []byte
+}
+
+func newBinaryWriter(w io.Writer) *binaryWriter {
+	return &binaryWriter{Writer: bufio.NewWriter(w)}
+}
+
+func (w *binaryWriter) uvarint(x uint64) {
+	w.buf = binary.AppendUvarint(w.buf[:0], x)
+	w.Write(w.buf)
+}
+
+func (w *binaryWriter) bytes(data []byte) {
+	w.uvarint(uint64(len(data)))
+	w.Write(data)
+}
+
+// header 写入magic, 格式版本和按ComponentType排序的组件信息.
+func (w *binaryWriter) header(magic string, schemas map[ComponentType]schema) {
+	types := make([]ComponentType, 0, len(schemas))
+	for t := range schemas {
+		types = append(types, t)
+	}
+	sort.Sort(TypesByType(types))
+
+	w.WriteString(magic)
+	w.uvarint(binaryVersion)
+	w.uvarint(uint64(len(types)))
+	for _, t := range types {
+		w.uvarint(uint64(t))
+		w.bytes([]byte(schemas[t].name))
+		w.uvarint(uint64(schemas[t].version))
+	}
+}
+
+func (w *binaryWriter) components(cs []EncodedComponent) {
+	w.uvarint(uint64(len(cs)))
+	for _, c := range cs {
+		w.uvarint(uint64(c.Type))
+		w.bytes(c.Data)
+	}
+}
+
+func (w *binaryWriter) ids(ids []EntityID) {
+	w.uvarint(uint64(len(ids)))
+	for _, id := range ids {
+		w.uvarint(uint64(id))
+	}
+}
+
+// binaryReader 记住第一个错误, 之后的读取都返回零值, 调用者读完一段之后检查err就可以了.
+type binaryReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func newBinaryReader(r io.Reader) *binaryReader {
+	return &binaryReader{r: bufio.NewReader(r)}
+}
+
+func (r *binaryReader) fail(err error) {
+	if r.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	x, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.fail(err)
+	}
+	return x
+}
+
+func (r *binaryReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n > maxPayload {
+		r.fail(fmt.Errorf("length %d too large", n))
+		return nil
+	}
+	if n <= chunkPayload {
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			r.fail(err)
+			return nil
+		}
+		return data
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r.r, int64(n)); err != nil {
+		r.fail(err)
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// header 读取并检查magic, 格式版本和组件信息. 组件的名字必须和当前注册的名字一致.
+func (r *binaryReader) header(magic string) (map[ComponentType]schema, error) {
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.r, m); err != nil {
+		r.fail(err)
+		return nil, r.err
+	}
+	if string(m) != magic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrBadSnapshot, m)
+	}
+	if version := r.uvarint(); r.err == nil && version != binaryVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrBadSnapshot, version)
+	}
+
+	count := r.uvarint()
+	schemas := make(map[ComponentType]schema)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		t := ComponentType(r.uvarint())
+		name := string(r.bytes())
+		version := uint32(r.uvarint())
+		if r.err != nil {
+			break
+		}
+		info, ok := DefaultRegistry.Info(t)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d (%s)", ErrComponentNotRegistered, t, name)
+		}
+		if info.Name != name {
+			return nil, fmt.Errorf("%w: %d is %s in the snapshot but %s now", ErrSchemaMismatch, t, name, info.Name)
+		}
+		schemas[t] = schema{name: name, version: version, codec: codecFor(info)}
+	}
+	return schemas, r.err
+}
+
+func (r *binaryReader) components() []EncodedComponent {
+	count := r.uvarint()
+	var cs []EncodedComponent
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		c := EncodedComponent{Type: ComponentType(r.uvarint())}
+		c.Data = r.bytes()
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+func (r *binaryReader) ids() []EntityID {
+	count := r.uvarint()
+	var ids []EntityID
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		ids = append(ids, EntityID(r.uvarint()))
+	}
+	return ids
+}
+
+// --- ReflectCodec -----------------------------------------------------------
+
+// ReflectCodec 用反射按字段顺序编码组件的导出字段, 没有字段名和类型信息, 所以很紧凑,
+// 但是增删改字段之后旧数据就不能直接解码了, 这时要提高版本号并用Migrate注册旧的布局.
+// 支持bool, 整数, 浮点数, string, 以及由它们组成的指针, 数组, slice, map和结构体.
+// 反射没法设置未导出的字段, 结构体里有未导出的字段时(ComponentOf这样不占空间的除外)编码和解码都返回错误,
+// 这样的组件要用SetComponentCodec设置自己的编码.
+type ReflectCodec struct {
+	goType     reflect.Type
+	version    uint32
+	migrations map[uint32]reflectMigration
+}
+
+type reflectMigration struct {
+	goType  reflect.Type
+	migrate func(old interface{}) (Component, error)
+}
+
+// NewReflectCodec 为组件T创建数据格式版本为version的ReflectCodec.
+func NewReflectCodec[T Component](version uint32) *ReflectCodec {
+	return &ReflectCodec{goType: reflect.TypeFor[T](), version: version}
+}
+
+// Migrate 注册旧版本from的数据布局: 旧数据按old的布局解码, 然后由fn转换成当前的组件.
+// old一般是旧版本组件结构体的一份拷贝, fn收到的是指向old的指针.
+func (c *ReflectCodec) Migrate(from uint32, old reflect.Type, fn func(old interface{}) (Component, error)) *ReflectCodec {
+	if c.migrations == nil {
+		c.migrations = make(map[uint32]reflectMigration)
+	}
+	c.migrations[from] = reflectMigration{goType: old, migrate: fn}
+	return c
+}
+
+func (c *ReflectCodec) Version() uint32 {
+	return c.version
+}
+
+func (c *ReflectCodec) Encode(component Component) ([]byte, error) {
+	v := reflect.ValueOf(component)
+	if v.Type() != c.goType {
+		return nil, fmt.Errorf("entitas: codec for %v got %v", c.goType, v.Type())
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *ReflectCodec) Decode(version uint32, data []byte) (Component, error) {
+	if version == c.version {
+		v := newComponentValue(c.goType)
+		if err := decodeAll(data, v.Elem()); err != nil {
+			return nil, err
+		}
+		return componentFromValue(c.goType, v), nil
+	}
+	m, ok := c.migrations[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v version %d, current is %d", ErrNoMigration, c.goType, version, c.version)
+	}
+	old := reflect.New(m.goType)
+	if err := decodeAll(data, old.Elem()); err != nil {
+		return nil, err
+	}
+	return m.migrate(old.Interface())
+}
+
+func decodeAll(data []byte, v reflect.Value) error {
+	r := bytes.NewReader(data)
+	if err := decodeValue(r, v); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("entitas: %d trailing bytes decoding %v", r.Len(), v.Type())
+	}
+	return nil
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	var scratch [binary.MaxVarintLen64]byte
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.Write(binary.AppendVarint(scratch[:0], v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.Write(binary.AppendUvarint(scratch[:0], v.Uint()))
+	case reflect.Float32:
+		buf.Write(binary.LittleEndian.AppendUint32(scratch[:0], math.Float32bits(float32(v.Float()))))
+	case reflect.Float64:
+		buf.Write(binary.LittleEndian.AppendUint64(scratch[:0], math.Float64bits(v.Float())))
+	case reflect.String:
+		buf.Write(binary.AppendUvarint(scratch[:0], uint64(v.Len())))
+		buf.WriteString(v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		return encodeValue(buf, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		// 长度加1, 0表示nil
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.Write(binary.AppendUvarint(scratch[:0], uint64(v.Len())+1))
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		// key按编码后的字节排序, 保证同样的map总是得到同样的数据.
+		type entry struct{ key, value []byte }
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var k, e bytes.Buffer
+			if err := encodeValue(&k, iter.Key()); err != nil {
+				return err
+			}
+			if err := encodeValue(&e, iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, entry{k.Bytes(), e.Bytes()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		buf.Write(binary.AppendUvarint(scratch[:0], uint64(len(entries))+1))
+		for _, e := range entries {
+			buf.Write(e.key)
+			buf.Write(e.value)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if skip, err := skipField(t, i, "use SetComponentCodec"); skip || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := encodeValue(buf, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("entitas: cannot encode %v", v.Type())
+	}
+	return nil
+}
+
+func decodeValue(r *bytes.Reader, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetUint(x)
+	case reflect.Float32:
+		var x uint32
+		if err := binary.Read(r, binary.LittleEndian, &x); err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(x)))
+	case reflect.Float64:
+		var x uint64
+		if err := binary.Read(r, binary.LittleEndian, &x); err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(x))
+	case reflect.String:
+		n, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		s := make([]byte, n)
+		if _, err := io.ReadFull(r, s); err != nil {
+			return err
+		}
+		v.SetString(string(s))
+	case reflect.Ptr:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		e := reflect.New(v.Type().Elem())
+		if err := decodeValue(r, e.Elem()); err != nil {
+			return err
+		}
+		v.Set(e)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := decodeValue(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		n, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), n-1, n-1)
+		for i := 0; i < n-1; i++ {
+			if err := decodeValue(r, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		n, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n-1)
+		for i := 0; i < n-1; i++ {
+			k := reflect.New(v.Type().Key()).Elem()
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(r, k); err != nil {
+				return err
+			}
+			if err := decodeValue(r, e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if skip, err := skipField(t, i, "use SetComponentCodec"); skip || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := decodeValue(r, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("entitas: cannot decode %v", v.Type())
+	}
+	return nil
+}
+
+// readLength 读取一个长度, 长度不能超过剩下的数据, 防止损坏的数据分配过多内存.
+func readLength(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.Len())+1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
@@ -0,0 +1,193 @@
+package entitas
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type binaryStats struct {
+	HP     int32
+	Speed  float32
+	Alive  bool
+	Name   string
+	Path   []uint16
+	Loot   map[string]int
+	Target *savedPosition
+	Grid   [2]int8
+}
+
+func (c *binaryStats) Type() ComponentType { return binaryStatsType }
+
+type binaryHidden struct {
+	ComponentOf[*binaryHidden]
+	value int
+}
+
+// binaryHealth 是v2的布局, v1只有一个表示百分比的Percent字段.
+type binaryHealth struct {
+	Current, Max int
+}
+
+type binaryHealthV1 struct {
+	Percent int
+}
+
+func (c *binaryHealth) Type() ComponentType   { return binaryHealthType }
+func (c *binaryHealthV1) Type() ComponentType { return binaryHealthType }
+
+var (
+	binaryStatsType  = RegisterComponentAs[*binaryStats](112, "stats")
+	binaryHealthType = RegisterComponentAs[*binaryHealth](113, "health")
+	binaryHiddenType = RegisterComponentAs[*binaryHidden](116, "hidden")
+)
+
+func TestBinary(t *testing.T) {
+	Convey("Given a pool with registered components", t, func() {
+		p := NewContext(0)
+		stats := &binaryStats{
+			HP:     -5,
+			Speed:  1.5,
+			Alive:  true,
+			Name:   "orc",
+			Path:   []uint16{1, 2, 3},
+			Loot:   map[string]int{"gold": 3, "gem": 1},
+			Target: &savedPosition{X: 1, Y: 2},
+			Grid:   [2]int8{-1, 1},
+		}
+		e0 := p.CreateEntity(stats, savedName{Name: "orc"})
+		p.CreateEntity(&savedPosition{X: 3})
+
+		var buf bytes.Buffer
+		So(SaveBinary(p, &buf), ShouldBeNil)
+		saved := buf.Bytes()
+
+		Convey("It is smaller than JSON and stable", func() {
+			var doc bytes.Buffer
+			SaveJSON(p, &doc)
+			So(len(saved), ShouldBeLessThan, doc.Len()/2)
+
+			var again bytes.Buffer
+			So(SaveBinary(p, &again), ShouldBeNil)
+			So(again.Bytes(), ShouldResemble, saved)
+		})
+
+		Convey("Loading restores entities, IDs and groups", func() {
+			q := NewContext(0)
+			g := q.Group(AllOf(binaryStatsType))
+			So(LoadBinary(q, bytes.NewReader(saved)), ShouldBeNil)
+			So(q.Count(), ShouldEqual, 2)
+			So(len(g.Entities()), ShouldEqual, 1)
+
+			e := g.Entities()[0]
+			So(e.ID(), ShouldEqual, e0.ID())
+			So(e.GetComponent(binaryStatsType), ShouldResemble, stats)
+			So(e.GetComponent(savedNameType), ShouldResemble, savedName{Name: "orc"})
+		})
+
+		Convey("Loading destroys the existing entities the normal way", func() {
+			q := NewContext(0)
+			g := q.Group(AllOf(binaryStatsType))
+			old := q.CreateEntity(&binaryStats{HP: 1})
+			h := q.Handle(old)
+			So(LoadBinary(q, bytes.NewReader(saved)), ShouldBeNil)
+			So(q.IsAlive(h), ShouldBeFalse)
+			e := g.Entities()[0]
+			So(e, ShouldEqual, old) // ID相同的entity被复用
+			So(q.Handle(e), ShouldNotEqual, h)
+			So(e.GetComponent(binaryStatsType), ShouldResemble, stats)
+		})
+
+		Convey("A load the pool rejects leaves the existing entities alone", func() {
+			q := NewContext(0)
+			So(q.MarkUnique(savedPositionType), ShouldBeNil)
+			old := q.CreateEntity(&binaryStats{HP: 1}, &savedPosition{X: 9})
+			h := q.Handle(old)
+			p.CreateEntity(&savedPosition{X: 4})
+			var conflict bytes.Buffer
+			So(SaveBinary(p, &conflict), ShouldBeNil)
+
+			err := LoadBinary(q, bytes.NewReader(conflict.Bytes()))
+			So(errors.Is(err, ErrUniqueComponentExists), ShouldBeTrue)
+			So(q.Count(), ShouldEqual, 1)
+			So(q.IsAlive(h), ShouldBeTrue)
+			So(old.GetComponent(binaryStatsType), ShouldResemble, &binaryStats{HP: 1})
+			So(old.GetComponent(savedPositionType), ShouldResemble, &savedPosition{X: 9})
+			So(q.UniqueEntity(savedPositionType), ShouldEqual, old)
+		})
+
+		Convey("Broken snapshots are rejected", func() {
+			So(errors.Is(LoadBinary(p, bytes.NewReader([]byte("nope"))), ErrBadSnapshot), ShouldBeTrue)
+			So(errors.Is(LoadBinary(p, bytes.NewReader(saved[:len(saved)-3])), ErrBadSnapshot), ShouldBeTrue)
+			So(p.Count(), ShouldEqual, 2)
+		})
+
+		Convey("A corrupt length doesn't allocate more than the input", func() {
+			var corrupt bytes.Buffer
+			w := newBinaryWriter(&corrupt)
+			w.uvarint(maxPayload)
+			w.Flush()
+			corrupt.WriteString("short")
+			r := newBinaryReader(&corrupt)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			So(r.bytes(), ShouldBeNil)
+			runtime.ReadMemStats(&after)
+			So(errors.Is(r.err, ErrBadSnapshot), ShouldBeTrue)
+			So(after.TotalAlloc-before.TotalAlloc, ShouldBeLessThan, 1<<20)
+		})
+	})
+
+	Convey("Components with unexported fields need their own codec", t, func() {
+		p := NewContext(0)
+		p.CreateEntity(&binaryHidden{value: 7})
+		var buf bytes.Buffer
+		err := SaveBinary(p, &buf)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "unexported field")
+	})
+
+	Convey("Given a snapshot written with an old component layout", t, func() {
+		defer SetComponentCodec(binaryHealthType, nil)
+		v1 := &ReflectCodec{goType: reflect.TypeFor[*binaryHealthV1](), version: 1}
+		SetComponentCodec(binaryHealthType, codecFunc{
+			version: 1,
+			encode: func(c Component) ([]byte, error) {
+				return v1.Encode(&binaryHealthV1{Percent: c.(*binaryHealth).Current})
+			},
+		})
+		p := NewContext(0)
+		p.CreateEntity(&binaryHealth{Current: 40, Max: 100})
+		var buf bytes.Buffer
+		So(SaveBinary(p, &buf), ShouldBeNil)
+
+		Convey("A registered migration upgrades it", func() {
+			SetComponentCodec(binaryHealthType, NewReflectCodec[*binaryHealth](2).Migrate(1, reflect.TypeFor[binaryHealthV1](),
+				func(old interface{}) (Component, error) {
+					return &binaryHealth{Current: old.(*binaryHealthV1).Percent, Max: 100}, nil
+				}))
+			q := NewContext(0)
+			So(LoadBinary(q, bytes.NewReader(buf.Bytes())), ShouldBeNil)
+			So(q.Entities()[0].GetComponent(binaryHealthType), ShouldResemble, &binaryHealth{Current: 40, Max: 100})
+		})
+
+		Convey("Loading fails without a migration", func() {
+			SetComponentCodec(binaryHealthType, NewReflectCodec[*binaryHealth](2))
+			q := NewContext(0)
+			So(errors.Is(LoadBinary(q, bytes.NewReader(buf.Bytes())), ErrNoMigration), ShouldBeTrue)
+		})
+	})
+}
+
+type codecFunc struct {
+	version uint32
+	encode  func(Component) ([]byte, error)
+}
+
+func (c codecFunc) Version() uint32                          { return c.version }
+func (c codecFunc) Encode(comp Component) ([]byte, error)    { return c.encode(comp) }
+func (c codecFunc) Decode(uint32, []byte) (Component, error) { return nil, errors.New("not supported") }
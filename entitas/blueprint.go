@@ -0,0 +1,166 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var ErrBlueprintRegistered = errors.New("blueprint already registered")
+
+// Cloner 是可选接口, 组件实现它之后CloneComponent用Clone()复制组件,
+// 不实现时用反射深拷贝.
+type Cloner interface {
+	Clone() Component
+}
+
+// Blueprint 描述一个有名字的组件集合, Context.Instantiate用它创建entity.
+// Components里是组件的默认值, 每次实例化都会被深拷贝, 所以不会在entity之间共享.
+type Blueprint struct {
+	Name       string
+	Components []Component
+}
+
+func NewBlueprint(name string, cs ...Component) *Blueprint {
+	return &Blueprint{Name: name, Components: cs}
+}
+
+// components 返回深拷贝的默认组件, overrides里同类型的组件替换默认值, 其它的追加在后面.
+// overrides不会被复制.
+func (b *Blueprint) components(overrides []Component) []Component {
+	cs := make([]Component, 0, len(b.Components)+len(overrides))
+	for _, c := range b.Components {
+		cs = append(cs, CloneComponent(c))
+	}
+	for _, o := range overrides {
+		replaced := false
+		for i, c := range cs {
+			if c.Type() == o.Type() {
+				cs[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			cs = append(cs, o)
+		}
+	}
+	return cs
+}
+
+func (b *Blueprint) String() string {
+	return fmt.Sprintf("Blueprint_%s(%v)", b.Name, b.Components)
+}
+
+// CloneComponent 深拷贝组件. 组件实现了Cloner时调用Clone(),
+// 否则用反射复制指针, 结构体, slice和map. 没有导出的字段只做浅拷贝,
+// 里面有slice或map之类的引用时组件需要自己实现Cloner. 不支持有环的数据.
+func CloneComponent(c Component) Component {
+	if cloner, ok := c.(Cloner); ok {
+		return cloner.Clone()
+	}
+	return deepCopy(reflect.ValueOf(c)).Interface().(Component)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.New(v.Type().Elem())
+		n.Elem().Set(deepCopy(v.Elem()))
+		return n
+	case reflect.Struct:
+		n := reflect.New(v.Type()).Elem()
+		n.Set(v)
+		for i := 0; i < n.NumField(); i++ {
+			if f := n.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return n
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return n
+	case reflect.Array:
+		n := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			n.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return n
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.New(v.Type()).Elem()
+		n.Set(deepCopy(v.Elem()))
+		return n
+	default:
+		return v
+	}
+}
+
+// --- Registry ---------------------------------------------------------------
+
+// BlueprintRegistry 按名字保存Blueprint.
+type BlueprintRegistry struct {
+	mu         sync.RWMutex
+	blueprints map[string]*Blueprint
+}
+
+// DefaultBlueprints 是默认的Blueprint注册表.
+var DefaultBlueprints = NewBlueprintRegistry()
+
+func NewBlueprintRegistry() *BlueprintRegistry {
+	return &BlueprintRegistry{blueprints: make(map[string]*Blueprint)}
+}
+
+// Register 注册Blueprint, 名字已经被用过时返回ErrBlueprintRegistered.
+func (r *BlueprintRegistry) Register(b *Blueprint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.blueprints[b.Name]; ok {
+		return fmt.Errorf("%w: %q", ErrBlueprintRegistered, b.Name)
+	}
+	r.blueprints[b.Name] = b
+	return nil
+}
+
+// Lookup 按名字查找Blueprint.
+func (r *BlueprintRegistry) Lookup(name string) (*Blueprint, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.blueprints[name]
+	return b, ok
+}
+
+// Names 返回所有Blueprint的名字, 按字母排序.
+func (r *BlueprintRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.blueprints))
+	for name := range r.blueprints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
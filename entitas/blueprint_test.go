@@ -0,0 +1,112 @@
+package entitas
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type inventoryComponent struct {
+	Items  []string
+	Counts map[string]int
+	Owner  *componentA
+	Extra  interface{}
+}
+
+func (c *inventoryComponent) Type() ComponentType { return ComponentE }
+
+type clonedComponent struct{ clones *int }
+
+func (c *clonedComponent) Type() ComponentType { return ComponentF }
+func (c *clonedComponent) Clone() Component {
+	*c.clones++
+	return &clonedComponent{clones: c.clones}
+}
+
+func TestCloneComponent(t *testing.T) {
+	Convey("Given a component with references", t, func() {
+		c := &inventoryComponent{
+			Items:  []string{"sword"},
+			Counts: map[string]int{"arrow": 10},
+			Owner:  &componentA{value: 1},
+			Extra:  []int{1},
+		}
+
+		Convey("It is deep copied", func() {
+			clone := CloneComponent(c).(*inventoryComponent)
+			So(clone, ShouldNotPointTo, c)
+			So(clone, ShouldResemble, c)
+
+			clone.Items[0] = "axe"
+			clone.Counts["arrow"] = 0
+			clone.Owner.value = 2
+			clone.Extra.([]int)[0] = 2
+			So(c.Items[0], ShouldEqual, "sword")
+			So(c.Counts["arrow"], ShouldEqual, 10)
+			So(c.Owner.value, ShouldEqual, 1)
+			So(c.Extra.([]int)[0], ShouldEqual, 1)
+		})
+
+		Convey("Cloner is preferred", func() {
+			clones := 0
+			CloneComponent(&clonedComponent{clones: &clones})
+			So(clones, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestBlueprint(t *testing.T) {
+	Convey("Given a blueprint", t, func() {
+		p := NewContext(0)
+		b := NewBlueprint("enemy", NewComponentA(1), &inventoryComponent{Items: []string{"sword"}})
+
+		Convey("Instantiated entities get copies of the defaults", func() {
+			e1 := p.Instantiate(b)
+			e2 := p.Instantiate(b)
+			So(e1.HasComponent(ComponentA, ComponentE), ShouldBeTrue)
+			So(e1.GetComponent(ComponentA), ShouldNotPointTo, e2.GetComponent(ComponentA))
+			e1.GetComponent(ComponentE).(*inventoryComponent).Items[0] = "axe"
+			So(e2.GetComponent(ComponentE).(*inventoryComponent).Items[0], ShouldEqual, "sword")
+			So(b.Components[1].(*inventoryComponent).Items[0], ShouldEqual, "sword")
+		})
+
+		Convey("Overrides replace defaults or add components", func() {
+			a := NewComponentA(5)
+			e := p.Instantiate(b, a, NewComponentB(1))
+			So(e.GetComponent(ComponentA), ShouldEqual, a)
+			So(e.HasComponent(ComponentB, ComponentE), ShouldBeTrue)
+			So(len(e.Components()), ShouldEqual, 3)
+		})
+
+		Convey("Groups see the entity once", func() {
+			g := p.Group(AllOf(ComponentA, ComponentE))
+			added := 0
+			g.AddCallback(EntityAdded, func(Group, Entity) { added++ })
+			e := p.Instantiate(b)
+			So(added, ShouldEqual, 1)
+			So(g.Entities(), ShouldResemble, []Entity{e})
+		})
+	})
+
+	Convey("Given a blueprint registry", t, func() {
+		r := NewBlueprintRegistry()
+		b := NewBlueprint("enemy", NewComponentA(1))
+		So(r.Register(b), ShouldBeNil)
+		So(r.Register(NewBlueprint("player")), ShouldBeNil)
+
+		Convey("Blueprints can be looked up by name", func() {
+			found, ok := r.Lookup("enemy")
+			So(ok, ShouldBeTrue)
+			So(found, ShouldEqual, b)
+			_, ok = r.Lookup("boss")
+			So(ok, ShouldBeFalse)
+			So(r.Names(), ShouldResemble, []string{"enemy", "player"})
+		})
+
+		Convey("Names can't be registered twice", func() {
+			err := r.Register(NewBlueprint("enemy"))
+			So(errors.Is(err, ErrBlueprintRegistered), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package entitas
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var changeTick atomic.Uint64
+
+// ChangeTick 返回当前的全局变化计数. 每次添加, 替换组件或者MarkChanged都会让它加1.
+func ChangeTick() uint64 {
+	return changeTick.Load()
+}
+
+// componentVersions 记录entity上每个组件最后一次变化时的ChangeTick.
+// 并行执行的系统写的组件类型不同, 但是可能同时MarkChanged同一个entity, 所以单独加锁.
+type componentVersions struct {
+	versionsMu sync.Mutex
+	versions   map[ComponentType]uint64
+}
+
+func (v *componentVersions) touch(t ComponentType) {
+	v.versionsMu.Lock()
+	defer v.versionsMu.Unlock()
+	if v.versions == nil {
+		v.versions = make(map[ComponentType]uint64)
+	}
+	v.versions[t] = changeTick.Add(1)
+}
+
+func (v *componentVersions) forget(t ComponentType) {
+	v.versionsMu.Lock()
+	defer v.versionsMu.Unlock()
+	delete(v.versions, t)
+}
+
+func (v *componentVersions) forgetAll() {
+	v.versionsMu.Lock()
+	defer v.versionsMu.Unlock()
+	v.versions = nil
+}
+
+func (v *componentVersions) ComponentVersion(t ComponentType) uint64 {
+	v.versionsMu.Lock()
+	defer v.versionsMu.Unlock()
+	return v.versions[t]
+}
+
+// ChangeFilter 找出组件在上一次Filter之后发生过变化的entity, 一般每个系统持有一个:
+//
+//	func (s *MovementSystem) Execute(dt time.Duration) {
+//		for _, e := range s.changed.Filter(s.group) {
+//			...
+//		}
+//	}
+//
+// 直接修改组件内部的值不会被发现, 需要调用Entity.MarkChanged或者用GetMut获取组件.
+type ChangeFilter struct {
+	types []ComponentType
+	since uint64
+}
+
+// Changed 创建一个ChangeFilter, ts里任意一种组件有变化的entity都会被选中.
+// 第一次Filter时所有有这些组件的entity都算作有变化.
+func Changed(ts ...ComponentType) *ChangeFilter {
+	return &ChangeFilter{types: ts}
+}
+
+// Matches 判断entity的组件在上一次Filter之后有没有变化, 不会推进ChangeFilter.
+func (f *ChangeFilter) Matches(e Entity) bool {
+	for _, t := range f.types {
+		if e.ComponentVersion(t) > f.since {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 返回g里有变化的entity, 然后把ChangeFilter推进到当前的ChangeTick.
+// 调用Filter的过程中发生的变化下一次还会再被选中一次.
+func (f *ChangeFilter) Filter(g Group) []Entity {
+	now := ChangeTick()
+	var changed []Entity
+	for _, e := range g.Entities() {
+		if f.Matches(e) {
+			changed = append(changed, e)
+		}
+	}
+	f.since = now
+	return changed
+}
+
+// Since 返回上一次Filter时的ChangeTick.
+func (f *ChangeFilter) Since() uint64 {
+	return f.since
+}
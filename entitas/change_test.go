@@ -0,0 +1,114 @@
+package entitas
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChangeTracking(t *testing.T) {
+	for name, p := range storages() {
+		Convey("Given an entity in a "+name+" pool", t, func() {
+			e := p.CreateEntity(NewComponentA(1), NewComponentB(1))
+
+			Convey("Adding components sets their versions", func() {
+				So(e.ComponentVersion(ComponentA), ShouldBeGreaterThan, 0)
+				So(e.ComponentVersion(ComponentB), ShouldBeGreaterThan, e.ComponentVersion(ComponentA))
+				So(e.ComponentVersion(ComponentC), ShouldEqual, 0)
+			})
+
+			Convey("MarkChanged bumps the version", func() {
+				before := e.ComponentVersion(ComponentA)
+				e.MarkChanged(ComponentA, ComponentC)
+				So(e.ComponentVersion(ComponentA), ShouldBeGreaterThan, before)
+				So(e.ComponentVersion(ComponentA), ShouldEqual, ChangeTick())
+				So(e.ComponentVersion(ComponentC), ShouldEqual, 0)
+			})
+
+			Convey("Replacing bumps the version", func() {
+				before := e.ComponentVersion(ComponentB)
+				e.ReplaceComponent(NewComponentB(2))
+				So(e.ComponentVersion(ComponentB), ShouldBeGreaterThan, before)
+			})
+
+			Convey("Removing clears the version", func() {
+				e.RemoveComponent(ComponentA)
+				So(e.ComponentVersion(ComponentA), ShouldEqual, 0)
+			})
+
+			Convey("GetMut marks the component changed", func() {
+				BindComponent[*componentA](ComponentA)
+				before := e.ComponentVersion(ComponentA)
+				c, ok := GetMut[*componentA](e)
+				So(ok, ShouldBeTrue)
+				c.value++
+				So(e.ComponentVersion(ComponentA), ShouldBeGreaterThan, before)
+			})
+
+			p.DestroyAllEntities()
+		})
+	}
+
+	Convey("Given a change filter", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		e1 := p.CreateEntity(NewComponentA(1))
+		e2 := p.CreateEntity(NewComponentA(2), NewComponentB(2))
+		changed := Changed(ComponentA)
+
+		Convey("The first run sees every entity", func() {
+			So(len(changed.Filter(g)), ShouldEqual, 2)
+
+			Convey("The next run sees nothing", func() {
+				So(changed.Filter(g), ShouldBeEmpty)
+			})
+
+			Convey("The next run sees entities changed in between", func() {
+				e2.MarkChanged(ComponentA)
+				e1.MarkChanged(ComponentB)
+				So(changed.Matches(e2), ShouldBeTrue)
+				So(changed.Filter(g), ShouldResemble, []Entity{e2})
+				So(changed.Filter(g), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestChangeTrackingInParallelSystems(t *testing.T) {
+	for name, p := range storages() {
+		Convey("Given two systems writing different components of one entity in a "+name+" pool", t, func() {
+			e := p.CreateEntity(NewComponentA(1), &savedPosition{X: 1})
+			var ready sync.WaitGroup
+			ready.Add(2)
+			const n = 100
+			markA := &accessTestSystem{writes: []ComponentType{ComponentA}, run: func() {
+				ready.Done()
+				ready.Wait()
+				for i := 0; i < n; i++ {
+					e.MarkChanged(ComponentA)
+					e.ComponentVersion(savedPositionType)
+				}
+			}}
+			mutPosition := &accessTestSystem{writes: []ComponentType{savedPositionType}, run: func() {
+				ready.Done()
+				ready.Wait()
+				for i := 0; i < n; i++ {
+					pos, _ := GetMut[*savedPosition](e)
+					pos.X++
+					e.ComponentVersion(ComponentA)
+				}
+			}}
+			w := NewWorld(p, WithWorkers(2)).AddSystem(markA, mutPosition)
+			w.Initialize()
+
+			Convey("They run together and both changes are recorded", func() {
+				before := ChangeTick()
+				So(w.Tick(0), ShouldBeNil)
+				So(e.ComponentVersion(ComponentA), ShouldBeGreaterThan, before)
+				So(e.ComponentVersion(savedPositionType), ShouldBeGreaterThan, before)
+				So(e.GetComponent(savedPositionType).(*savedPosition).X, ShouldEqual, 1+n)
+			})
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package entitas
+
+import "sync"
+
+type commandKind uint8
+
+const (
+	commandCreate commandKind = iota
+	commandDestroy
+	commandAdd
+	commandReplace
+	commandRemove
+)
+
+type command struct {
+	kind       commandKind
+	entity     Entity
+	handle     EntityHandle // 记录命令时entity的handle
+	pending    *PendingEntity
+	components []Component
+	types      []ComponentType
+}
+
+// EntityCommandBuffer 记录对entity的结构性修改, 在Playback时按记录的顺序执行.
+// 遍历group的时候不能直接创建/销毁entity或者增删组件, 应该先记录到buffer里, 遍历结束后再统一执行.
+// 记录操作可以在多个goroutine里同时进行, 但只有单个goroutine记录时执行顺序才是确定的.
+type EntityCommandBuffer struct {
+	mu       sync.Mutex
+	commands []command
+}
+
+func NewEntityCommandBuffer() *EntityCommandBuffer {
+	return &EntityCommandBuffer{}
+}
+
+// PendingEntity 是在buffer里创建的entity, Playback之后才真正存在.
+// 可以在同一个buffer里继续对它增删组件或者销毁它.
+type PendingEntity struct {
+	buffer *EntityCommandBuffer
+	entity Entity
+	handle EntityHandle
+}
+
+// Entity 返回Playback创建的entity, Playback之前返回nil.
+func (p *PendingEntity) Entity() Entity {
+	return p.entity
+}
+
+// Handle 返回Playback创建的entity的handle, Playback之前返回InvalidEntityHandle.
+func (p *PendingEntity) Handle() EntityHandle {
+	return p.handle
+}
+
+func (p *PendingEntity) AddComponent(cs ...Component) *PendingEntity {
+	p.buffer.record(command{kind: commandAdd, pending: p, components: cs})
+	return p
+}
+
+func (p *PendingEntity) ReplaceComponent(cs ...Component) *PendingEntity {
+	p.buffer.record(command{kind: commandReplace, pending: p, components: cs})
+	return p
+}
+
+func (p *PendingEntity) RemoveComponent(ts ...ComponentType) *PendingEntity {
+	p.buffer.record(command{kind: commandRemove, pending: p, types: ts})
+	return p
+}
+
+func (p *PendingEntity) Destroy() {
+	p.buffer.record(command{kind: commandDestroy, pending: p})
+}
+
+func (b *EntityCommandBuffer) CreateEntity(cs ...Component) *PendingEntity {
+	p := &PendingEntity{buffer: b}
+	b.record(command{kind: commandCreate, pending: p, components: cs})
+	return p
+}
+
+func (b *EntityCommandBuffer) DestroyEntity(e Entity) {
+	b.record(command{kind: commandDestroy, entity: e, handle: handleOf(e)})
+}
+
+func (b *EntityCommandBuffer) AddComponent(e Entity, cs ...Component) {
+	b.record(command{kind: commandAdd, entity: e, handle: handleOf(e), components: cs})
+}
+
+func (b *EntityCommandBuffer) ReplaceComponent(e Entity, cs ...Component) {
+	b.record(command{kind: commandReplace, entity: e, handle: handleOf(e), components: cs})
+}
+
+func (b *EntityCommandBuffer) RemoveComponent(e Entity, ts ...ComponentType) {
+	b.record(command{kind: commandRemove, entity: e, handle: handleOf(e), types: ts})
+}
+
+// Append 把other里的命令移动到b的末尾, other会被清空.
+// other里创建的PendingEntity之后记录的命令也会进入b.
+func (b *EntityCommandBuffer) Append(other *EntityCommandBuffer) {
+	other.mu.Lock()
+	commands := other.commands
+	other.commands = nil
+	other.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, cmd := range commands {
+		if cmd.kind == commandCreate {
+			cmd.pending.buffer = b
+		}
+	}
+	b.commands = append(b.commands, commands...)
+}
+
+// Len 返回还没有执行的命令数量.
+func (b *EntityCommandBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.commands)
+}
+
+// Clear 丢弃所有还没有执行的命令.
+func (b *EntityCommandBuffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands = nil
+}
+
+// Playback 按记录顺序执行所有命令并清空buffer.
+// 命令记录的是entity在记录时的handle, 目标entity在记录之后被销毁(包括被buffer里更早的命令销毁)时跳过对应的命令,
+// 即使entity对象已经被复用. 不属于ctx的entity上的命令也会被跳过.
+// 返回执行过程中遇到的第一个错误, 出错的命令不会影响后面的命令.
+func (b *EntityCommandBuffer) Playback(ctx Context) error {
+	b.mu.Lock()
+	commands := b.commands
+	b.commands = nil
+	b.mu.Unlock()
+
+	var firstErr error
+	for _, cmd := range commands {
+		if cmd.kind == commandCreate {
+			e, err := tryCreateEntity(ctx, cmd.components...)
+			if err != nil {
+				// entity没有被创建, pending.handle保持无效, 后面针对它的命令都会被跳过.
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			cmd.pending.entity = e
+			cmd.pending.handle = ctx.Handle(e)
+			continue
+		}
+
+		h := cmd.handle
+		if cmd.pending != nil {
+			h = cmd.pending.handle
+		}
+		e, ok := ctx.Resolve(h)
+		if !ok || (cmd.pending == nil && e != cmd.entity) {
+			continue
+		}
+
+		var err error
+		switch cmd.kind {
+		case commandDestroy:
+			ctx.DestroyEntity(e)
+		case commandAdd:
+			err = e.AddComponent(cmd.components...)
+		case commandReplace:
+			err = e.ReplaceComponent(cmd.components...)
+		case commandRemove:
+			err = e.RemoveComponent(cmd.types...)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (b *EntityCommandBuffer) record(cmd command) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands = append(b.commands, cmd)
+}
@@ -0,0 +1,107 @@
+package entitas
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEntityCommandBuffer(t *testing.T) {
+	Convey("Given a pool and a command buffer", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		b := NewEntityCommandBuffer()
+		e1 := p.CreateEntity(NewComponentA(1))
+		e2 := p.CreateEntity(NewComponentA(2))
+
+		Convey("It defers structural changes until playback", func() {
+			for _, e := range g.Entities() {
+				b.DestroyEntity(e)
+			}
+			So(len(g.Entities()), ShouldEqual, 2)
+			So(b.Len(), ShouldEqual, 2)
+
+			So(b.Playback(p), ShouldBeNil)
+			So(g.Entities(), ShouldBeEmpty)
+			So(b.Len(), ShouldEqual, 0)
+		})
+
+		Convey("It plays back commands in recorded order", func() {
+			b.RemoveComponent(e1, ComponentA)
+			b.AddComponent(e1, NewComponentA(10))
+			b.ReplaceComponent(e2, NewComponentB(2))
+			So(b.Playback(p), ShouldBeNil)
+
+			a, _ := Get[*componentA](e1)
+			So(a.value, ShouldEqual, 10)
+			So(e2.HasComponent(ComponentB), ShouldBeTrue)
+		})
+
+		Convey("It creates entities which can be referenced inside the buffer", func() {
+			created := b.CreateEntity(NewComponentA(3))
+			created.AddComponent(NewComponentB(3))
+			So(created.Entity(), ShouldBeNil)
+
+			So(b.Playback(p), ShouldBeNil)
+			So(created.Entity(), ShouldNotBeNil)
+			So(created.Entity().HasComponent(ComponentA, ComponentB), ShouldBeTrue)
+			So(p.IsAlive(created.Handle()), ShouldBeTrue)
+			So(g.Entities(), ShouldContain, created.Entity())
+		})
+
+		Convey("It skips commands on entities destroyed earlier in the buffer", func() {
+			b.DestroyEntity(e1)
+			b.DestroyEntity(e1)
+			recycled := b.CreateEntity()
+			b.AddComponent(e1, NewComponentB(1))
+			So(b.Playback(p), ShouldBeNil)
+
+			So(recycled.Entity(), ShouldEqual, e1)
+			So(recycled.Entity().HasComponent(ComponentB), ShouldBeFalse)
+		})
+
+		Convey("It skips commands on entities destroyed and recycled after recording", func() {
+			b.AddComponent(e1, NewComponentB(1))
+			b.DestroyEntity(e1)
+			p.DestroyEntity(e1)
+			recycled := p.CreateEntity(NewComponentA(6))
+			So(recycled, ShouldEqual, e1)
+			So(b.Playback(p), ShouldBeNil)
+
+			So(p.HasEntity(recycled), ShouldBeTrue)
+			So(recycled.HasComponent(ComponentB), ShouldBeFalse)
+		})
+
+		Convey("It skips commands on entities of another context", func() {
+			other := NewContext(0).CreateEntity(NewComponentA(7))
+			So(other.ID(), ShouldEqual, e1.ID())
+			b.AddComponent(other, NewComponentB(7))
+			So(b.Playback(p), ShouldBeNil)
+			So(e1.HasComponent(ComponentB), ShouldBeFalse)
+			So(other.HasComponent(ComponentB), ShouldBeFalse)
+		})
+
+		Convey("It skips commands on entities destroyed in the buffer before creation", func() {
+			created := b.CreateEntity(NewComponentA(4))
+			created.Destroy()
+			created.AddComponent(NewComponentB(4))
+			So(b.Playback(p), ShouldBeNil)
+			So(p.IsAlive(created.Handle()), ShouldBeFalse)
+		})
+
+		Convey("It returns the first error and keeps playing back", func() {
+			b.AddComponent(e1, NewComponentA(5))
+			b.RemoveComponent(e2, ComponentB)
+			b.AddComponent(e2, NewComponentC())
+			So(b.Playback(p), ShouldEqual, ErrComponentExists)
+			So(e2.HasComponent(ComponentC), ShouldBeTrue)
+		})
+
+		Convey("It discards commands when cleared", func() {
+			b.DestroyEntity(e1)
+			b.Clear()
+			So(b.Playback(p), ShouldBeNil)
+			So(p.HasEntity(e1), ShouldBeTrue)
+		})
+	})
+}
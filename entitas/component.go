@@ -1,6 +1,6 @@
 package entitas
 
-import "fmt"
+import "strconv"
 
 type ComponentType uint16
 
@@ -16,16 +16,21 @@ func (c ComponentType) ComponentTypes() []ComponentType {
 	return []ComponentType{c}
 }
 
+// String 返回组件在DefaultRegistry里注册的名字, 没有注册或者名字不能被ParseMatcher解析时返回数字.
 func (c ComponentType) String() string {
-	return fmt.Sprintf("%d", c)
+	if info, ok := DefaultRegistry.Info(c); ok && isIdentifier(info.Name) {
+		return info.Name
+	}
+	return strconv.Itoa(int(c))
 }
 
 func (c ComponentType) Equals(m Matcher) bool {
-	types := m.ComponentTypes()
-	if len(types) == 1 {
-		return types[0] == c
-	}
-	return false
+	t, ok := m.(ComponentType)
+	return ok && t == c
+}
+
+func (c ComponentType) key() string {
+	return strconv.Itoa(int(c))
 }
 
 type TypesByType []ComponentType
@@ -0,0 +1,231 @@
+package entitas
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrDeltaMismatch = errors.New("delta does not match context")
+
+const deltaMagic = "ECSD"
+
+// Delta 是两个Snapshot之间的差别, ApplyDelta用它把处于旧状态的Context变成新状态.
+type Delta struct {
+	Created   []EntityID    // 新创建的entity, 组件在Entities里它的Added中
+	Destroyed []EntityID    // 被销毁的entity
+	Entities  []EntityDelta // 组件有变化的entity, 按ID排序
+	schemas   map[ComponentType]schema
+}
+
+// EntityDelta 是一个entity的组件变化. Added和Changed里是新状态的组件数据.
+type EntityDelta struct {
+	ID      EntityID
+	Added   []EncodedComponent
+	Changed []EncodedComponent
+	Removed []ComponentType
+}
+
+// Empty 判断两个状态是否完全一样.
+func (d *Delta) Empty() bool {
+	return len(d.Created) == 0 && len(d.Destroyed) == 0 && len(d.Entities) == 0
+}
+
+// Diff 计算从from到to的变化. 组件数据按编码之后的字节比较,
+// 所以编码不确定的组件(例如自定义codec里遍历map)每次都会被当作有变化.
+// Snapshot里没有handle的代数, 销毁之后ID被重用的entity会被当作同一个entity的组件变化.
+func Diff(from, to *Snapshot) *Delta {
+	d := &Delta{schemas: make(map[ComponentType]schema)}
+	i, j := 0, 0
+	for i < len(from.entities) || j < len(to.entities) {
+		switch {
+		case j == len(to.entities) || (i < len(from.entities) && from.entities[i].id < to.entities[j].id):
+			d.Destroyed = append(d.Destroyed, from.entities[i].id)
+			i++
+		case i == len(from.entities) || to.entities[j].id < from.entities[i].id:
+			e := to.entities[j]
+			d.Created = append(d.Created, e.id)
+			d.Entities = append(d.Entities, EntityDelta{ID: e.id, Added: e.components})
+			j++
+		default:
+			if ed, ok := diffEntity(from.entities[i], to.entities[j]); ok {
+				d.Entities = append(d.Entities, ed)
+			}
+			i++
+			j++
+		}
+	}
+	for _, ed := range d.Entities {
+		for _, c := range ed.Added {
+			d.schemas[c.Type] = to.schemas[c.Type]
+		}
+		for _, c := range ed.Changed {
+			d.schemas[c.Type] = to.schemas[c.Type]
+		}
+	}
+	return d
+}
+
+func diffEntity(from, to snapshotEntity) (EntityDelta, bool) {
+	ed := EntityDelta{ID: to.id}
+	i, j := 0, 0
+	for i < len(from.components) || j < len(to.components) {
+		switch {
+		case j == len(to.components) || (i < len(from.components) && from.components[i].Type < to.components[j].Type):
+			ed.Removed = append(ed.Removed, from.components[i].Type)
+			i++
+		case i == len(from.components) || to.components[j].Type < from.components[i].Type:
+			ed.Added = append(ed.Added, to.components[j])
+			j++
+		default:
+			if !bytes.Equal(from.components[i].Data, to.components[j].Data) {
+				ed.Changed = append(ed.Changed, to.components[j])
+			}
+			i++
+			j++
+		}
+	}
+	return ed, len(ed.Added) > 0 || len(ed.Changed) > 0 || len(ed.Removed) > 0
+}
+
+// ApplyDelta 把d应用到ctx上. ctx必须处于d的旧状态: 被销毁和被修改的entity必须存在, 被删除的组件必须存在,
+// 新创建的entity的ID不能被占用, 否则返回ErrDeltaMismatch, ctx不会被修改.
+// 检查和修改在同一次加锁里完成, 开启WithLocking时别的goroutine看不到修改了一半的ctx.
+// 新创建的entity和CreateEntity一样先加好组件再加入group; 已有的entity的组件被逐个删除和替换,
+// 所以group和observer会收到对应的事件.
+// 唯一组件和索引的检查只能在修改时进行, 被拒绝时返回错误, 这时之前的修改已经生效了.
+func ApplyDelta(ctx Context, d *Delta) error {
+	p, ok := ctx.(*pool)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrUnsupportedContext, ctx)
+	}
+
+	type change struct {
+		set     []Component
+		removed []ComponentType
+	}
+	changes := make(map[EntityID]change, len(d.Entities))
+	for _, ed := range d.Entities {
+		var c change
+		for _, encoded := range append(append([]EncodedComponent(nil), ed.Added...), ed.Changed...) {
+			component, err := decodeComponent(d.schemas, encoded)
+			if err != nil {
+				return fmt.Errorf("%w on entity %d", err, ed.ID)
+			}
+			c.set = append(c.set, component)
+		}
+		c.removed = ed.Removed
+		changes[ed.ID] = c
+	}
+
+	created := make(map[EntityID]struct{}, len(d.Created))
+	for _, id := range d.Created {
+		created[id] = struct{}{}
+	}
+
+	p.lock()
+	defer p.unlock()
+	entities := make(map[EntityID]contextEntity, len(d.Destroyed)+len(d.Entities))
+	for _, id := range d.Destroyed {
+		e, ok := p.entities[id]
+		if !ok {
+			return fmt.Errorf("%w: entity %d to destroy does not exist", ErrDeltaMismatch, id)
+		}
+		entities[id] = e.(contextEntity)
+	}
+	for _, id := range d.Created {
+		if _, ok := p.entities[id]; ok {
+			return fmt.Errorf("%w: entity %d to create already exists", ErrDeltaMismatch, id)
+		}
+	}
+	for _, ed := range d.Entities {
+		if _, ok := created[ed.ID]; ok {
+			continue
+		}
+		e, ok := p.entities[ed.ID]
+		if !ok {
+			return fmt.Errorf("%w: entity %d to change does not exist", ErrDeltaMismatch, ed.ID)
+		}
+		for _, t := range ed.Removed {
+			if !e.HasComponent(t) {
+				return fmt.Errorf("%w: component %v to remove from entity %d does not exist", ErrDeltaMismatch, t, ed.ID)
+			}
+		}
+		entities[ed.ID] = e.(contextEntity)
+	}
+
+	for _, id := range d.Destroyed {
+		p.destroyEntity(entities[id])
+	}
+	for _, id := range d.Created {
+		if _, err := p.addEntity(p.entityWithID(id), changes[id].set...); err != nil {
+			return fmt.Errorf("%w on entity %d", err, id)
+		}
+	}
+	for _, ed := range d.Entities {
+		if _, ok := created[ed.ID]; ok {
+			continue
+		}
+		e, c := entities[ed.ID], changes[ed.ID]
+		if err := e.removeComponent(c.removed...); err != nil {
+			return fmt.Errorf("%w on entity %d", err, ed.ID)
+		}
+		if err := e.replaceComponent(c.set...); err != nil {
+			return fmt.Errorf("%w on entity %d", err, ed.ID)
+		}
+	}
+	return nil
+}
+
+// --- Encoding ---------------------------------------------------------------
+
+// WriteDelta 用和SaveBinary相同的编码写出d. 格式是:
+//
+//	magic "ECSD", 格式版本, 组件信息(同SaveBinary)
+//	新创建的entity ID, 被销毁的entity ID
+//	有变化的entity数量, 每个entity的ID, 添加的组件, 修改的组件, 删除的组件类型
+func WriteDelta(w io.Writer, d *Delta) error {
+	bw := newBinaryWriter(w)
+	bw.header(deltaMagic, d.schemas)
+	bw.ids(d.Created)
+	bw.ids(d.Destroyed)
+	bw.uvarint(uint64(len(d.Entities)))
+	for _, ed := range d.Entities {
+		bw.uvarint(uint64(ed.ID))
+		bw.components(ed.Added)
+		bw.components(ed.Changed)
+		bw.uvarint(uint64(len(ed.Removed)))
+		for _, t := range ed.Removed {
+			bw.uvarint(uint64(t))
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadDelta 读取WriteDelta写出的数据. 组件数据在ApplyDelta时才解码.
+func ReadDelta(r io.Reader) (*Delta, error) {
+	br := newBinaryReader(r)
+	schemas, err := br.header(deltaMagic)
+	if err != nil {
+		return nil, err
+	}
+	d := &Delta{schemas: schemas}
+	d.Created = br.ids()
+	d.Destroyed = br.ids()
+	count := br.uvarint()
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		ed := EntityDelta{ID: EntityID(br.uvarint())}
+		ed.Added = br.components()
+		ed.Changed = br.components()
+		removed := br.uvarint()
+		for k := uint64(0); k < removed && br.err == nil; k++ {
+			ed.Removed = append(ed.Removed, ComponentType(br.uvarint()))
+		}
+		d.Entities = append(d.Entities, ed)
+	}
+	if br.err != nil {
+		return nil, br.err
+	}
+	return d, nil
+}
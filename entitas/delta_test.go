@@ -0,0 +1,102 @@
+package entitas
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func saveBinary(ctx Context) []byte {
+	var buf bytes.Buffer
+	So(SaveBinary(ctx, &buf), ShouldBeNil)
+	return buf.Bytes()
+}
+
+func TestDelta(t *testing.T) {
+	Convey("Given two states of a pool", t, func() {
+		p := NewContext(0)
+		e0 := p.CreateEntity(&savedPosition{X: 1}, savedName{Name: "a"})
+		e1 := p.CreateEntity(&savedPosition{X: 2})
+		e2 := p.CreateEntity(savedName{Name: "c"})
+		e3 := p.CreateEntity(&savedPosition{X: 4})
+		from, err := TakeSnapshot(p)
+		So(err, ShouldBeNil)
+		state := saveBinary(p)
+
+		e0.ReplaceComponent(&savedPosition{X: 10})
+		e0.RemoveComponent(savedNameType)
+		e1.AddComponent(savedName{Name: "b"})
+		e3.ReplaceComponent(&savedPosition{X: 4})
+		e4 := p.CreateEntity(savedName{Name: "e"})
+		p.DestroyEntity(e2)
+		to, err := TakeSnapshot(p)
+		So(err, ShouldBeNil)
+
+		d := Diff(from, to)
+
+		Convey("It records created, destroyed and changed entities", func() {
+			So(d.Destroyed, ShouldResemble, []EntityID{e2.ID()})
+			So(d.Created, ShouldResemble, []EntityID{e4.ID()})
+			So(len(d.Entities), ShouldEqual, 3)
+
+			So(d.Entities[0].ID, ShouldEqual, e0.ID())
+			So(len(d.Entities[0].Changed), ShouldEqual, 1)
+			So(d.Entities[0].Removed, ShouldResemble, []ComponentType{savedNameType})
+			So(d.Entities[1].ID, ShouldEqual, e1.ID())
+			So(len(d.Entities[1].Added), ShouldEqual, 1)
+			So(d.Entities[2].ID, ShouldEqual, e4.ID())
+		})
+
+		Convey("A snapshot has no difference to itself", func() {
+			So(Diff(to, to).Empty(), ShouldBeTrue)
+		})
+
+		Convey("Applying it brings another pool from the old to the new state", func() {
+			q := NewContext(0)
+			So(LoadBinary(q, bytes.NewReader(state)), ShouldBeNil)
+			names := q.Group(AllOf(savedNameType))
+			So(len(names.Entities()), ShouldEqual, 2)
+
+			So(ApplyDelta(q, d), ShouldBeNil)
+			So(saveBinary(q), ShouldResemble, saveBinary(p))
+			So(len(names.Entities()), ShouldEqual, 2)
+		})
+
+		Convey("It survives encoding", func() {
+			var buf bytes.Buffer
+			So(WriteDelta(&buf, d), ShouldBeNil)
+			read, err := ReadDelta(&buf)
+			So(err, ShouldBeNil)
+
+			q := NewContext(0)
+			So(LoadBinary(q, bytes.NewReader(state)), ShouldBeNil)
+			So(ApplyDelta(q, read), ShouldBeNil)
+			So(saveBinary(q), ShouldResemble, saveBinary(p))
+		})
+
+		Convey("Applying it to a pool in another state fails", func() {
+			q := NewContext(0)
+			So(errors.Is(ApplyDelta(q, d), ErrDeltaMismatch), ShouldBeTrue)
+			So(q.Count(), ShouldEqual, 0)
+		})
+
+		Convey("A missing component to remove fails before anything is applied", func() {
+			q := NewContext(0)
+			So(LoadBinary(q, bytes.NewReader(state)), ShouldBeNil)
+			q.Entities()[0].RemoveComponent(savedNameType)
+			before := saveBinary(q)
+
+			So(errors.Is(ApplyDelta(q, d), ErrDeltaMismatch), ShouldBeTrue)
+			So(saveBinary(q), ShouldResemble, before)
+		})
+
+		Convey("It applies to a locking pool", func() {
+			q := NewContext(0, WithLocking())
+			So(LoadBinary(q, bytes.NewReader(state)), ShouldBeNil)
+			So(ApplyDelta(q, d), ShouldBeNil)
+			So(saveBinary(q), ShouldResemble, saveBinary(p))
+		})
+	})
+}
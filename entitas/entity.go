@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync/atomic"
 )
 
 var (
@@ -13,15 +14,23 @@ var (
 
 type EntityID uint
 
+type EntitiesByID []Entity
+
+func (es EntitiesByID) Len() int           { return len(es) }
+func (es EntitiesByID) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es EntitiesByID) Less(i, j int) bool { return es[i].ID() < es[j].ID() }
+
 type Entity interface {
 	AddComponent(cs ...Component) error
 	RebuildComponentIndex()
-	ReplaceComponent(cs ...Component)
+	ReplaceComponent(cs ...Component) error // 替换组件, 不存在时添加. 被Context拒绝时返回错误, 这个组件和后面的组件都不会被修改
 	WillRemoveComponent(ts ...ComponentType) error
 	RemoveComponent(ts ...ComponentType) error
 	RemoveAllComponents()
 	RemoveAllCallbacks()
-	AddCallback(ev ComponentEvent, cb ComponentCallback)
+	AddCallback(ev ComponentEvent, cb ComponentCallback) Subscription
+	AddReplaceCallback(cb ComponentReplacedCallback) Subscription
+	RemoveCallback(s Subscription) bool // 删除一个回调, 返回是否找到
 	HasCallbacks() bool
 
 	ID() EntityID
@@ -34,6 +43,14 @@ type Entity interface {
 	LinearSearchComponent(t ComponentType) Component
 	Components() []Component
 	ComponentIndices() []ComponentType
+	// ForEachComponent 按类型的顺序对每个组件调用fn, 不分配内存. 遍历的是调用时的组件类型,
+	// fn里删除的还没遍历到的组件会被跳过, 被替换的组件传给fn的是新的值, 新添加的组件不会被遍历.
+	ForEachComponent(fn func(Component))
+
+	// MarkChanged 标记组件被直接修改过, 组件的版本号会更新为新的ChangeTick. 不触发任何事件.
+	MarkChanged(ts ...ComponentType)
+	// ComponentVersion 返回组件最后一次被添加, 替换或者MarkChanged时的ChangeTick, 组件不存在时返回0.
+	ComponentVersion(t ComponentType) uint64
 }
 
 type ComponentEvent uint
@@ -47,64 +64,193 @@ const (
 
 type ComponentCallback func(Entity, Component)
 
+// ComponentReplacedCallback 在ComponentReplaced事件时被调用, 同时收到替换前后的组件.
+type ComponentReplacedCallback func(e Entity, prev, cur Component)
+
+// contextEntity 由Context创建的entity实现.
+// 开启WithLocking时修改entity的方法会加Context的锁, Context自己已经持有锁时通过小写的方法修改entity.
+// 小写的addCallback注册的是Context内部的回调, 总是立即调用.
+type contextEntity interface {
+	Entity
+	setLock(l *contextLock)
+	setValidator(v func(e Entity, c Component) error)
+	generation() uint32
+	setGeneration(gen uint32)
+	addComponent(cs ...Component) error
+	replaceComponent(cs ...Component) error
+	removeComponent(ts ...ComponentType) error
+	removeAllComponents()
+	removeAllCallbacks()
+	addCallback(ev ComponentEvent, cb ComponentCallback) Subscription
+	addReplaceCallback(cb ComponentReplacedCallback) Subscription
+}
+
+// entityLock 是entity共享的Context锁, 没有开启WithLocking时为nil.
+type entityLock struct {
+	mu *contextLock
+}
+
+func (l *entityLock) setLock(mu *contextLock) {
+	l.mu = mu
+}
+
+func (l *entityLock) lock() {
+	l.mu.lock()
+}
+
+func (l *entityLock) unlock() {
+	l.mu.unlock()
+}
+
+// deferred 包装用户通过AddCallback注册的回调, 开启WithLocking时推迟到解锁之后调用.
+func (l *entityLock) deferred(cb ComponentCallback) ComponentCallback {
+	return func(e Entity, c Component) {
+		if l.mu == nil {
+			cb(e, c)
+			return
+		}
+		l.mu.dispatch(func() { cb(e, c) })
+	}
+}
+
+func (l *entityLock) deferredReplace(cb ComponentReplacedCallback) ComponentReplacedCallback {
+	return func(e Entity, prev, cur Component) {
+		if l.mu == nil {
+			cb(e, prev, cur)
+			return
+		}
+		l.mu.dispatch(func() { cb(e, prev, cur) })
+	}
+}
+
+// entityValidator 是Context在组件被添加或者替换之前做的检查, 返回错误时entity不会被修改.
+type entityValidator struct {
+	validator func(e Entity, c Component) error
+}
+
+func (v *entityValidator) setValidator(fn func(e Entity, c Component) error) {
+	v.validator = fn
+}
+
+func (v *entityValidator) validate(e Entity, c Component) error {
+	if v.validator == nil {
+		return nil
+	}
+	return v.validator(e, c)
+}
+
 type entity struct {
-	id         EntityID
-	sortedComponents []Component
-	components map[ComponentType]Component
-	callbacks  map[ComponentEvent][]ComponentCallback
+	entityLock
+	entityGeneration
+	entityValidator
+	componentVersions
+	id               EntityID
+	sortedComponents []Component  // 按类型排好序的组件, 组件变化时更新
+	walking          atomic.Int32 // 正在进行的ForEachComponent数量, 不为0时修改sortedComponents之前要先复制
+	components       map[ComponentType]Component
+	callbacks        map[ComponentEvent]callbackList[ComponentCallback]
+	replaceCallbacks callbackList[ComponentReplacedCallback]
 }
 
 func NewEntity(id int) Entity {
 	return &entity{
 		id:         EntityID(id),
 		components: make(map[ComponentType]Component),
-		callbacks:  make(map[ComponentEvent][]ComponentCallback),
+		callbacks:  make(map[ComponentEvent]callbackList[ComponentCallback]),
 	}
 }
 
 func (e *entity) AddComponent(cs ...Component) error {
+	e.lock()
+	defer e.unlock()
+	return e.addComponent(cs...)
+}
+
+func (e *entity) addComponent(cs ...Component) error {
 	for _, c := range cs {
 		if e.HasComponent(c.Type()) {
 			return ErrComponentExists
 		}
+		if err := e.validate(e, c); err != nil {
+			return err
+		}
 		e.components[c.Type()] = c
+		e.index(c)
+		e.touch(c.Type())
 		e.callback(ComponentAdded, c)
 	}
 	return nil
 }
 
-func (e *entity) RebuildComponentIndex() {
-	ln := len(e.sortedComponents)
-	if cap(e.sortedComponents) >= len(e.components) {
-		e.sortedComponents = e.sortedComponents[:len(e.components)]
-		fmt.Printf("change length %d -> %d\n", ln, len(e.components))
-	} else {
-		e.sortedComponents = make([]Component, len(e.components), len(e.components) * 4 / 3)
-		fmt.Printf("create new array, length %d -> %d\n", ln, len(e.components))
+// RebuildComponentIndex 不再需要调用, sortedComponents在组件变化时就更新好了.
+func (e *entity) RebuildComponentIndex() {}
+
+// index 把c放进sortedComponents, 已经有同类型的组件时替换它.
+func (e *entity) index(c Component) {
+	e.ownIndex()
+	coms := e.sortedComponents
+	i := sort.Search(len(coms), func(i int) bool { return coms[i].Type() >= c.Type() })
+	if i < len(coms) && coms[i].Type() == c.Type() {
+		coms[i] = c
+		return
 	}
-	idx := 0
-	for _, c := range e.components {
-		e.sortedComponents[idx] = c
-		idx += 1
+	coms = append(coms, nil)
+	copy(coms[i+1:], coms[i:])
+	coms[i] = c
+	e.sortedComponents = coms
+}
+
+func (e *entity) unindex(t ComponentType) {
+	coms := e.sortedComponents
+	i := sort.Search(len(coms), func(i int) bool { return coms[i].Type() >= t })
+	if i == len(coms) || coms[i].Type() != t {
+		return
+	}
+	e.ownIndex()
+	e.sortedComponents = removeComponentAt(e.sortedComponents, i)
+}
+
+// ownIndex 在修改sortedComponents之前调用, 正在进行的ForEachComponent遍历的slice不会被修改.
+func (e *entity) ownIndex() {
+	if e.walking.Load() > 0 {
+		e.sortedComponents = append(make([]Component, 0, len(e.sortedComponents)+1), e.sortedComponents...)
 	}
-	sort.Slice(e.sortedComponents, func(i, j int) bool {
-		return e.sortedComponents[i].Type() < e.sortedComponents[j].Type()
-	})
 }
 
-func (e *entity) ReplaceComponent(cs ...Component) {
+func removeComponentAt(coms []Component, i int) []Component {
+	copy(coms[i:], coms[i+1:])
+	coms[len(coms)-1] = nil
+	return coms[:len(coms)-1]
+}
+
+func (e *entity) ReplaceComponent(cs ...Component) error {
+	e.lock()
+	defer e.unlock()
+	return e.replaceComponent(cs...)
+}
+
+func (e *entity) replaceComponent(cs ...Component) error {
 	for _, c := range cs {
-		has := e.HasComponent(c.Type())
+		if err := e.validate(e, c); err != nil {
+			return err
+		}
+		prev, has := e.components[c.Type()]
 		e.components[c.Type()] = c
+		e.index(c)
+		e.touch(c.Type())
 		if has {
 			e.callback(ComponentReplaced, c)
+			e.replaceCallback(prev, c)
 		} else {
 			e.callback(ComponentAdded, c)
 		}
 	}
+	return nil
 }
 
 func (e *entity) WillRemoveComponent(ts ...ComponentType) error {
+	e.lock()
+	defer e.unlock()
 	for _, t := range ts {
 		c, err := e.Component(t)
 		if err != nil {
@@ -116,6 +262,12 @@ func (e *entity) WillRemoveComponent(ts ...ComponentType) error {
 }
 
 func (e *entity) RemoveComponent(ts ...ComponentType) error {
+	e.lock()
+	defer e.unlock()
+	return e.removeComponent(ts...)
+}
+
+func (e *entity) removeComponent(ts ...ComponentType) error {
 	for _, t := range ts {
 		c, err := e.Component(t)
 		if err != nil {
@@ -123,12 +275,20 @@ func (e *entity) RemoveComponent(ts ...ComponentType) error {
 		}
 		e.callback(ComponentWillBeRemoved, c)
 		delete(e.components, t)
+		e.unindex(t)
+		e.forget(t)
 		e.callback(ComponentRemoved, c)
 	}
 	return nil
 }
 
 func (e *entity) RemoveAllComponents() {
+	e.lock()
+	defer e.unlock()
+	e.removeAllComponents()
+}
+
+func (e *entity) removeAllComponents() {
 	components := e.components
 
 	for _, c := range components {
@@ -136,30 +296,85 @@ func (e *entity) RemoveAllComponents() {
 	}
 
 	e.components = make(map[ComponentType]Component)
+	if e.walking.Load() > 0 {
+		e.sortedComponents = nil
+	} else {
+		clear(e.sortedComponents)
+		e.sortedComponents = e.sortedComponents[:0]
+	}
+	e.forgetAll()
 
 	for _, c := range components {
 		e.callback(ComponentRemoved, c)
 	}
 }
 
+func (e *entity) MarkChanged(ts ...ComponentType) {
+	e.lock()
+	defer e.unlock()
+	for _, t := range ts {
+		if e.HasComponent(t) {
+			e.touch(t)
+		}
+	}
+}
+
 func (e *entity) ID() EntityID {
 	return e.id
 }
 
-func (e *entity) AddCallback(ev ComponentEvent, cb ComponentCallback) {
-	cbs, ok := e.callbacks[ev]
-	if !ok {
-		cbs = make([]ComponentCallback, 0)
+func (e *entity) AddCallback(ev ComponentEvent, cb ComponentCallback) Subscription {
+	e.lock()
+	defer e.unlock()
+	return e.addCallback(ev, e.deferred(cb))
+}
+
+func (e *entity) addCallback(ev ComponentEvent, cb ComponentCallback) Subscription {
+	cbs := e.callbacks[ev]
+	sub := cbs.add(cb)
+	e.callbacks[ev] = cbs
+	return sub
+}
+
+func (e *entity) AddReplaceCallback(cb ComponentReplacedCallback) Subscription {
+	e.lock()
+	defer e.unlock()
+	return e.addReplaceCallback(e.deferredReplace(cb))
+}
+
+func (e *entity) addReplaceCallback(cb ComponentReplacedCallback) Subscription {
+	return e.replaceCallbacks.add(cb)
+}
+
+func (e *entity) RemoveCallback(sub Subscription) bool {
+	e.lock()
+	defer e.unlock()
+	for ev, cbs := range e.callbacks {
+		if cbs.remove(sub) {
+			if len(cbs) == 0 {
+				delete(e.callbacks, ev)
+			} else {
+				e.callbacks[ev] = cbs
+			}
+			return true
+		}
 	}
-	e.callbacks[ev] = append(cbs, cb)
+	return e.replaceCallbacks.remove(sub)
 }
 
 func (e *entity) HasCallbacks() bool {
-	return len(e.callbacks) > 0
+	return len(e.callbacks) > 0 || len(e.replaceCallbacks) > 0
 }
 
 func (e *entity) RemoveAllCallbacks() {
-	e.callbacks = make(map[ComponentEvent][]ComponentCallback)
+	e.lock()
+	defer e.unlock()
+	e.removeAllCallbacks()
+}
+
+func (e *entity) removeAllCallbacks() {
+	e.callbacks = make(map[ComponentEvent]callbackList[ComponentCallback])
+	e.replaceCallbacks = nil
 }
 
 func (e *entity) HasComponent(ts ...ComponentType) bool {
@@ -188,6 +403,7 @@ func (e *entity) Component(t ComponentType) (Component, error) {
 	return c, nil
 }
 
+// GetComponent 组件少的时候在sortedComponents里二分查找, 否则查map.
 func (e *entity) GetComponent(t ComponentType) Component {
 	if len(e.sortedComponents) < 64 {
 		return e.BinarySearchComponent(t)
@@ -245,6 +461,16 @@ func (e *entity) Components() []Component {
 	return components
 }
 
+func (e *entity) ForEachComponent(fn func(Component)) {
+	e.walking.Add(1)
+	defer e.walking.Add(-1)
+	for _, c := range e.sortedComponents {
+		if cur, ok := e.components[c.Type()]; ok {
+			fn(cur)
+		}
+	}
+}
+
 func (e *entity) ComponentIndices() []ComponentType {
 	types := make([]ComponentType, len(e.components))
 	i := 0
@@ -262,7 +488,13 @@ func (e *entity) String() string {
 func (e *entity) callback(ev ComponentEvent, c Component) {
 	if cbs, ok := e.callbacks[ev]; ok {
 		for _, cb := range cbs {
-			cb(e, c)
+			cb.fn(e, c)
 		}
 	}
 }
+
+func (e *entity) replaceCallback(prev, cur Component) {
+	for _, cb := range e.replaceCallbacks {
+		cb.fn(e, prev, cur)
+	}
+}
@@ -294,3 +294,34 @@ func BenchmarkEntityRemoveAll(b *testing.B) {
 		e.RemoveAllComponents()
 	}
 }
+
+func TestReplaceCallback(t *testing.T) {
+	for name, p := range storages() {
+		Convey("Given an entity from a "+name+" pool with a replace callback", t, func() {
+			old := NewComponentA(1)
+			e := p.CreateEntity(old)
+			var calls int
+			var gotPrev, gotCur Component
+			e.AddReplaceCallback(func(e Entity, prev, cur Component) {
+				calls++
+				gotPrev, gotCur = prev, cur
+			})
+			So(e.HasCallbacks(), ShouldBeTrue)
+
+			Convey("It receives the previous and the new component", func() {
+				cur := NewComponentA(2)
+				e.ReplaceComponent(cur)
+				So(calls, ShouldEqual, 1)
+				So(gotPrev, ShouldEqual, old)
+				So(gotCur, ShouldEqual, cur)
+			})
+
+			Convey("It is not called when the component is added", func() {
+				e.ReplaceComponent(NewComponentB(1))
+				So(calls, ShouldEqual, 0)
+			})
+
+			p.DestroyAllEntities()
+		})
+	}
+}
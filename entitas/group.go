@@ -1,13 +1,40 @@
 package entitas
 
+import (
+	"sort"
+	"sync"
+)
+
 type Group interface {
-	Entities() []Entity                        // 获取所有的组件。
-	HandleEntity(e Entity)                     // 将组件添加到或者移除出当前group（判断标准是group.matcher）
-	UpdateEntity(e Entity)                     // 触发事件，触发一下Remove再触发一下Add事件
-	WillRemoveEntity(e Entity)                 // 触发WillRemove事件
-	Matches(e Entity) bool                     // 判断是不是应该包含参数entity（判断标准是group.matcher）
-	ContainsEntity(e Entity) bool              // 判断是不是已经包含entity
-	AddCallback(e GroupEvent, c GroupCallback) // -
+	Entities() []Entity                                     // 获取所有的entity, 按ID排序, 或者按NewSortedGroup的比较函数排序. 返回的slice不能修改
+	HandleEntity(e Entity)                                  // 将组件添加到或者移除出当前group（判断标准是group.matcher）
+	UpdateEntity(e Entity, prev, cur Component)             // 组件被替换. 匹配结果不变时触发Remove, Add, Updated事件, 否则加入或者移除entity
+	WillRemoveEntity(e Entity)                              // 触发WillRemove事件
+	Matches(e Entity) bool                                  // 判断是不是应该包含参数entity（判断标准是group.matcher）
+	ContainsEntity(e Entity) bool                           // 判断是不是已经包含entity
+	AddCallback(e GroupEvent, c GroupCallback) Subscription // -
+	AddUpdateCallback(c GroupUpdateCallback) Subscription   // 注册EntityUpdated事件的回调, 可以拿到替换前后的组件
+	RemoveCallback(s Subscription) bool                     // 删除一个回调或者检查函数, 返回是否找到
+
+	// AddValidator 注册一个检查函数. Context在添加或者替换组件之前, 用修改之后的entity调用会匹配它的group的检查函数,
+	// 返回错误时entity不会被修改, 错误由AddComponent/ReplaceComponent返回.
+	// 检查函数在Context的锁里被调用, 不能修改Context.
+	AddValidator(v GroupValidator) Subscription
+	// Validate 用所有检查函数检查entity, 返回第一个错误.
+	Validate(e Entity) error
+
+	// ForEach 按Entities()的顺序对每个entity调用fn, 不分配内存.
+	// 遍历的是调用时的entities, fn里对group的修改从下一次遍历开始生效.
+	ForEach(fn func(Entity))
+	// Iter 返回一个遍历group的游标, 和ForEach一样遍历调用时的entities, 不分配内存.
+	Iter() GroupIter
+
+	// ParallelForEach 把entities分成workers批, 在多个goroutine里对每个entity调用fn.
+	// fn里只能修改组件内部的值, 不能增删替换组件或者创建销毁entity,
+	// 结构性修改要通过ParallelForEachDeferred记录到命令缓冲里.
+	ParallelForEach(workers int, fn func(Entity))
+	// ParallelForEachChunk 和ParallelForEach一样, 但是每批entity只调用一次fn.
+	ParallelForEachChunk(workers int, fn func(entities []Entity))
 }
 
 type GroupEvent uint
@@ -16,38 +43,60 @@ const (
 	EntityAdded GroupEvent = iota
 	EntityWillBeRemoved
 	EntityRemoved
+	EntityUpdated // group里的entity的组件被替换了
 )
 
 type GroupCallback func(Group, Entity)
 
+// GroupUpdateCallback 在EntityUpdated事件时被调用, prev和cur是替换前后的组件.
+type GroupUpdateCallback func(g Group, e Entity, prev, cur Component)
+
+// GroupValidator 检查entity是否可以进入group, e是添加或者替换组件之后的entity.
+type GroupValidator func(g Group, e Entity) error
+
 type group struct {
-	entities         map[EntityID]Entity
-	cache            []Entity
-	cacheInvalidated bool
-	matcher          Matcher
-	callbacks        map[GroupEvent][]GroupCallback
+	entities        map[EntityID]Entity
+	sorted          []Entity     // entities排好序的结果, 添加和删除时直接插入或者移除
+	cache           []Entity     // Entities()返回的sorted的拷贝, 修改sorted之后清空, 下次调用时再复制
+	walking         bool         // 正在进行的ForEach或者Iter在遍历sorted, 修改之前要先复制
+	iterating       int          // 正在遍历sorted的ForEach和Iter数量, 都结束之后sorted又可以直接修改
+	epoch           int          // sorted被复制的次数, 复制之前开始的遍历结束时不再计数
+	mu              sync.Mutex   // 保护entities, sorted和callbacks, 回调在锁外执行
+	events          *contextLock // 属于开启了WithLocking的Context时, 回调推迟到Context解锁之后
+	matcher         Matcher
+	compare         func(a, b Entity) int
+	callbacks       map[GroupEvent]callbackList[GroupCallback]
+	syncCallbacks   map[GroupEvent]callbackList[GroupCallback] // 不推迟的回调, 索引用它在锁里更新
+	updateCallbacks callbackList[GroupUpdateCallback]
+	validators      callbackList[GroupValidator]
 }
 
+// NewGroup 创建一个按entity ID排序的group.
 func NewGroup(matcher Matcher) Group {
-	return &group{
-		entities:         make(map[EntityID]Entity),
-		cache:            make([]Entity, 0),
-		cacheInvalidated: false,
-		matcher:          matcher,
-		callbacks:        make(map[GroupEvent][]GroupCallback),
+	return NewSortedGroup(matcher, nil)
+}
+
+// NewSortedGroup 创建一个按compare排序的group, compare返回负数表示a排在b前面,
+// 返回0时按ID排序, compare为nil时只按ID排序.
+// compare在group的锁里被调用, 只能读取matcher里的组件, 不能访问group.
+// 组件被替换时entity会重新排序, 直接修改组件内部的值不会, 需要用ReplaceComponent.
+func NewSortedGroup(matcher Matcher, compare func(a, b Entity) int) Group {
+	g := &group{
+		entities:      make(map[EntityID]Entity),
+		sorted:        make([]Entity, 0),
+		matcher:       matcher,
+		compare:       compare,
+		callbacks:     make(map[GroupEvent]callbackList[GroupCallback]),
+		syncCallbacks: make(map[GroupEvent]callbackList[GroupCallback]),
 	}
+	return g
 }
 
 func (g *group) Entities() []Entity {
-	if g.cacheInvalidated {
-		cache := make([]Entity, len(g.entities))
-		i := 0
-		for _, e := range g.entities {
-			cache[i] = e
-			i++
-		}
-		g.cache = cache
-		g.cacheInvalidated = false
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cache == nil {
+		g.cache = append(make([]Entity, 0, len(g.sorted)), g.sorted...)
 	}
 	return g.cache
 }
@@ -60,15 +109,49 @@ func (g *group) HandleEntity(e Entity) {
 	}
 }
 
-func (g *group) UpdateEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; ok {
+func (g *group) UpdateEntity(e Entity, prev, cur Component) {
+	contains := g.ContainsEntity(e)
+	if matches := g.matcher.Matches(e); matches != contains {
+		// 按组件的值过滤的matcher在替换之后可能不再匹配, 或者开始匹配.
+		if matches {
+			g.addEntity(e)
+		} else {
+			g.removeEntity(e)
+		}
+		return
+	}
+	if contains {
+		if g.compare != nil {
+			g.mu.Lock()
+			g.remove(e)
+			g.insert(e)
+			g.mu.Unlock()
+		}
 		g.callback(EntityRemoved, e)
 		g.callback(EntityAdded, e)
+		g.callback(EntityUpdated, e)
+		g.mu.Lock()
+		cs := g.updateCallbacks
+		g.mu.Unlock()
+		if len(cs) == 0 {
+			return
+		}
+		if g.events != nil {
+			g.events.dispatch(func() { g.updated(cs, e, prev, cur) })
+		} else {
+			g.updated(cs, e, prev, cur)
+		}
+	}
+}
+
+func (g *group) updated(cs callbackList[GroupUpdateCallback], e Entity, prev, cur Component) {
+	for _, c := range cs {
+		c.fn(g, e, prev, cur)
 	}
 }
 
 func (g *group) WillRemoveEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; ok {
+	if g.ContainsEntity(e) {
 		g.callback(EntityWillBeRemoved, e)
 	}
 }
@@ -78,47 +161,254 @@ func (g *group) Matches(e Entity) bool {
 }
 
 func (g *group) ContainsEntity(e Entity) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if _, ok := g.entities[e.ID()]; ok {
 		return true
 	}
 	return false
 }
 
-func (g *group) AddCallback(ev GroupEvent, c GroupCallback) {
-	cs, ok := g.callbacks[ev]
-	if !ok {
-		cs = make([]GroupCallback, 0)
+func (g *group) AddCallback(ev GroupEvent, c GroupCallback) Subscription {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cs := g.callbacks[ev]
+	sub := cs.add(c)
+	g.callbacks[ev] = cs
+	return sub
+}
+
+// addSyncCallback 和AddCallback一样, 但是开启WithLocking时回调也不推迟, 在产生事件的地方直接调用.
+func (g *group) addSyncCallback(ev GroupEvent, c GroupCallback) Subscription {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cs := g.syncCallbacks[ev]
+	sub := cs.add(c)
+	g.syncCallbacks[ev] = cs
+	return sub
+}
+
+func (g *group) AddUpdateCallback(c GroupUpdateCallback) Subscription {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.updateCallbacks.add(c)
+}
+
+func (g *group) AddValidator(v GroupValidator) Subscription {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.validators.add(v)
+}
+
+func (g *group) Validate(e Entity) error {
+	g.mu.Lock()
+	vs := g.validators
+	g.mu.Unlock()
+	for _, v := range vs {
+		if err := v.fn(g, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *group) RemoveCallback(sub Subscription) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, callbacks := range []map[GroupEvent]callbackList[GroupCallback]{g.callbacks, g.syncCallbacks} {
+		for ev, cs := range callbacks {
+			if cs.remove(sub) {
+				callbacks[ev] = cs
+				return true
+			}
+		}
+	}
+	return g.updateCallbacks.remove(sub) || g.validators.remove(sub)
+}
+
+func (g *group) ForEach(fn func(Entity)) {
+	entities, epoch := g.walk()
+	defer g.endWalk(epoch)
+	for _, e := range entities {
+		fn(e)
+	}
+}
+
+func (g *group) Iter() GroupIter {
+	entities, epoch := g.walk()
+	return GroupIter{g: g, entities: entities, epoch: epoch, i: -1}
+}
+
+// walk 返回ForEach和Iter要遍历的entities. 和Entities()不同, 遍历结束之后调用endWalk,
+// 之后添加和删除entity可以直接修改sorted, 每帧遍历之后修改group也不需要复制.
+func (g *group) walk() ([]Entity, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.walking = true
+	g.iterating++
+	return g.sorted, g.epoch
+}
+
+// endWalk 结束epoch时开始的遍历. sorted已经被复制过时, 这次遍历用的是旧的数组, 不用再计数.
+func (g *group) endWalk(epoch int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if epoch != g.epoch {
+		return
 	}
-	g.callbacks[ev] = append(cs, c)
+	if g.iterating--; g.iterating == 0 {
+		g.walking = false
+	}
+}
+
+func (g *group) ParallelForEach(workers int, fn func(Entity)) {
+	g.ParallelForEachChunk(workers, func(entities []Entity) {
+		for _, e := range entities {
+			fn(e)
+		}
+	})
+}
+
+func (g *group) ParallelForEachChunk(workers int, fn func(entities []Entity)) {
+	entities := g.Entities()
+	forEachBatch(entities, batchCount(len(entities), workers), func(_ int, batch []Entity) {
+		fn(batch)
+	})
 }
 
 func (g *group) addEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; !ok {
+	g.mu.Lock()
+	_, ok := g.entities[e.ID()]
+	if !ok {
 		g.entities[e.ID()] = e
-		if g.cacheInvalidated == false {
-			g.cache = append(g.cache, e)
-		}
+		g.insert(e)
+	}
+	g.mu.Unlock()
+	if !ok {
 		g.callback(EntityAdded, e)
 	}
 }
 
 func (g *group) removeEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; ok {
+	g.mu.Lock()
+	old, ok := g.entities[e.ID()]
+	if ok {
 		delete(g.entities, e.ID())
-		if i := findIndex(g.cache, e); i != -1 {
-			g.cache = nil
-			g.cacheInvalidated = true
-		}
+		g.remove(old)
+	}
+	g.mu.Unlock()
+	if ok {
 		g.callback(EntityRemoved, e)
 	}
 }
 
+func (g *group) less(a, b Entity) bool {
+	if g.compare != nil {
+		if c := g.compare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return a.ID() < b.ID()
+}
+
+// own 在修改sorted之前调用. 只有ForEach或者Iter正在遍历sorted时才复制, Entities()返回的是另外的拷贝.
+func (g *group) own() {
+	g.cache = nil
+	if g.walking {
+		g.sorted = append(make([]Entity, 0, len(g.sorted)+1), g.sorted...)
+		g.walking = false
+		g.iterating = 0
+		g.epoch++
+	}
+}
+
+func (g *group) insert(e Entity) {
+	g.own()
+	i := sort.Search(len(g.sorted), func(i int) bool { return g.less(e, g.sorted[i]) })
+	g.sorted = append(g.sorted, nil)
+	copy(g.sorted[i+1:], g.sorted[i:])
+	g.sorted[i] = e
+}
+
+func (g *group) remove(e Entity) {
+	i := g.indexOf(e)
+	if i == -1 {
+		return
+	}
+	g.own()
+	copy(g.sorted[i:], g.sorted[i+1:])
+	g.sorted[len(g.sorted)-1] = nil
+	g.sorted = g.sorted[:len(g.sorted)-1]
+}
+
+// indexOf 按ID排序时二分查找. 有比较函数时entity的组件可能已经被删除或者修改了, 只能逐个比较.
+func (g *group) indexOf(e Entity) int {
+	if g.compare != nil {
+		return findIndex(g.sorted, e)
+	}
+	i := sort.Search(len(g.sorted), func(i int) bool { return g.sorted[i].ID() >= e.ID() })
+	if i < len(g.sorted) && g.sorted[i] == e {
+		return i
+	}
+	return -1
+}
+
 func (g *group) callback(ev GroupEvent, e Entity) {
-	if cs, ok := g.callbacks[ev]; ok {
-		for _, c := range cs {
-			c(g, e)
+	g.mu.Lock()
+	cs, direct := g.callbacks[ev], g.syncCallbacks[ev]
+	g.mu.Unlock()
+	g.call(direct, e)
+	if len(cs) == 0 {
+		return
+	}
+	if g.events != nil {
+		g.events.dispatch(func() { g.call(cs, e) })
+	} else {
+		g.call(cs, e)
+	}
+}
+
+func (g *group) call(cs callbackList[GroupCallback], e Entity) {
+	for _, c := range cs {
+		c.fn(g, e)
+	}
+}
+
+// GroupIter 是Group.Iter返回的游标, 不分配内存:
+//
+//	for it := g.Iter(); it.Next(); {
+//		e := it.Entity()
+//		...
+//	}
+//
+// 没有遍历完就退出循环也可以, 这时group下一次被修改时会复制一次entities.
+type GroupIter struct {
+	g        *group
+	entities []Entity
+	epoch    int
+	i        int
+}
+
+// Next 移动到下一个entity, 没有更多entity时返回false.
+func (it *GroupIter) Next() bool {
+	if it.i < len(it.entities) {
+		it.i++
+		if it.i == len(it.entities) && it.g != nil {
+			it.g.endWalk(it.epoch)
+			it.g = nil
 		}
 	}
+	return it.i < len(it.entities)
+}
+
+// Entity 返回当前的entity, 只能在Next返回true之后调用.
+func (it *GroupIter) Entity() Entity {
+	return it.entities[it.i]
+}
+
+// Len 返回游标遍历的entity总数.
+func (it *GroupIter) Len() int {
+	return len(it.entities)
 }
 
 func findIndex(entities []Entity, e Entity) int {
@@ -1,5 +1,10 @@
 package entitas
 
+import (
+	"sort"
+	"sync"
+)
+
 type ObserverEvent uint
 
 const (
@@ -9,21 +14,30 @@ const (
 )
 
 type GroupObserver interface {
-	CollectedEntities() []Entity
+	CollectedEntities() []Entity // 按ID排序
+	ForEach(fn func(Entity))     // 按ID的顺序遍历收集到的entity, 不分配内存. fn里可以清空observer
 	Activate()
 	Deactivate()
 	ClearCollectedEntities()
+	Dispose() // 从group上删除回调, 之后observer不再收集entity
 }
 
 type groupObserver struct {
-	entities map[Entity]struct{}
-	active   bool
+	mu            sync.Mutex
+	entities      map[Entity]EntityHandle // 收集到的entity和最后一次收集时的handle
+	sorted        []Entity                // 收集到的entity, 按ID排序. 清空时保留底层数组给下一帧用
+	shared        bool                    // 正在进行的ForEach在遍历sorted, 修改之前要先复制一份
+	iterating     int                     // 正在进行的ForEach数量
+	active        bool
+	group         Group
+	subscriptions []Subscription
 }
 
 func NewGroupObserver(group Group, event ObserverEvent) *groupObserver {
 	observer := &groupObserver{
-		entities: make(map[Entity]struct{}),
+		entities: make(map[Entity]EntityHandle),
 		active:   true,
+		group:    group,
 	}
 
 	callback := func(group Group, entity Entity) {
@@ -32,45 +46,118 @@ func NewGroupObserver(group Group, event ObserverEvent) *groupObserver {
 
 	switch event {
 	case ObserverEntityAdded:
-		group.AddCallback(EntityAdded, callback)
+		observer.subscribe(EntityAdded, callback)
 	case ObserverEntityRemoved:
-		group.AddCallback(EntityRemoved, callback)
+		observer.subscribe(EntityRemoved, callback)
 	case ObserverEntityAddedOrRemoved:
-		group.AddCallback(EntityAdded, callback)
-		group.AddCallback(EntityRemoved, callback)
+		observer.subscribe(EntityAdded, callback)
+		observer.subscribe(EntityRemoved, callback)
 	}
 
 	return observer
 }
 
-func (observer *groupObserver) CollectedEntities() []Entity {
-	entities := make([]Entity, len(observer.entities))
+func (observer *groupObserver) subscribe(ev GroupEvent, callback GroupCallback) {
+	observer.subscriptions = append(observer.subscriptions, observer.group.AddCallback(ev, callback))
+}
 
-	i := 0
+func (observer *groupObserver) CollectedEntities() []Entity {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	entities := make([]Entity, len(observer.sorted))
+	copy(entities, observer.sorted)
+	return entities
+}
 
-	for entity, _ := range observer.entities {
-		entities[i] = entity
-		i++
+func (observer *groupObserver) ForEach(fn func(Entity)) {
+	observer.mu.Lock()
+	entities := observer.sorted
+	observer.shared = true
+	observer.iterating++
+	observer.mu.Unlock()
+	defer func() {
+		observer.mu.Lock()
+		if observer.iterating--; observer.iterating == 0 {
+			observer.shared = false
+		}
+		observer.mu.Unlock()
+	}()
+	for _, e := range entities {
+		fn(e)
 	}
+}
 
-	return entities
+// forEachHandle 按ID的顺序对收集到的entity和它最后一次被收集时的handle调用fn.
+func (observer *groupObserver) forEachHandle(fn func(Entity, EntityHandle)) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	for _, e := range observer.sorted {
+		fn(e, observer.entities[e])
+	}
 }
 
 func (observer *groupObserver) Activate() {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
 	observer.active = true
 }
 
 func (observer *groupObserver) Deactivate() {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
 	observer.active = false
-	observer.ClearCollectedEntities()
+	observer.clear()
 }
 
 func (observer *groupObserver) ClearCollectedEntities() {
-	observer.entities = make(map[Entity]struct{})
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	observer.clear()
+}
+
+func (observer *groupObserver) Dispose() {
+	observer.mu.Lock()
+	subscriptions := observer.subscriptions
+	observer.subscriptions = nil
+	observer.active = false
+	observer.clear()
+	observer.mu.Unlock()
+	for _, sub := range subscriptions {
+		observer.group.RemoveCallback(sub)
+	}
+}
+
+// clear 清空收集到的entity, 调用者持有锁.
+func (observer *groupObserver) clear() {
+	clear(observer.entities)
+	if observer.shared {
+		observer.sorted = nil
+		observer.shared = false
+	} else {
+		clear(observer.sorted)
+		observer.sorted = observer.sorted[:0]
+	}
 }
 
 func addEntity(observer *groupObserver, group Group, entity Entity) {
-	if observer.active {
-		observer.entities[entity] = struct{}{}
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	if !observer.active {
+		return
+	}
+	_, ok := observer.entities[entity]
+	observer.entities[entity] = handleOf(entity)
+	if ok {
+		return
+	}
+	if observer.shared {
+		observer.sorted = append([]Entity(nil), observer.sorted...)
+		observer.shared = false
 	}
+	sorted := observer.sorted
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i].ID() > entity.ID() })
+	sorted = append(sorted, nil)
+	copy(sorted[i+1:], sorted[i:])
+	sorted[i] = entity
+	observer.sorted = sorted
 }
@@ -9,7 +9,7 @@ import (
 func TestGroupObserver(t *testing.T) {
 
 	Convey("Given a pool, group & new group observer", t, func() {
-		pool := NewContext(NumComponents, 0)
+		pool := NewContext(0)
 		group := pool.Group(AllOf(ComponentA))
 
 		Convey("When observing with eventType ObserverEntityAdded", func() {
@@ -30,6 +30,17 @@ func TestGroupObserver(t *testing.T) {
 				So(observer.CollectedEntities(), ShouldResemble, []Entity{entityA})
 			})
 
+			Convey("It returns collected entities ordered by ID", func() {
+				e1 := pool.CreateEntity()
+				e2 := pool.CreateEntity()
+				e3 := pool.CreateEntity()
+				e3.AddComponent(NewComponentA(3))
+				e1.AddComponent(NewComponentA(1))
+				e2.AddComponent(NewComponentA(2))
+
+				So(observer.CollectedEntities(), ShouldResemble, []Entity{e1, e2, e3})
+			})
+
 			Convey("It collects entites only once", func() {
 				entity := pool.CreateEntity()
 				entity.AddComponent(NewComponentA(1))
@@ -127,3 +138,89 @@ func TestGroupObserver(t *testing.T) {
 		})
 	})
 }
+
+func TestGroupObserverForEach(t *testing.T) {
+	Convey("Given a group observer", t, func() {
+		pool := NewContext(0)
+		observer := NewGroupObserver(pool.Group(AllOf(ComponentA)), ObserverEntityAdded)
+		e0 := pool.CreateEntity()
+		e1 := pool.CreateEntity()
+		e2 := pool.CreateEntity()
+		e2.AddComponent(NewComponentA(2))
+		e0.AddComponent(NewComponentA(0))
+
+		Convey("It visits collected entities ordered by ID", func() {
+			var visited []Entity
+			observer.ForEach(func(e Entity) { visited = append(visited, e) })
+			So(visited, ShouldResemble, []Entity{e0, e2})
+		})
+
+		Convey("It can be cleared while visiting", func() {
+			var visited []Entity
+			observer.ForEach(func(e Entity) {
+				visited = append(visited, e)
+				observer.ClearCollectedEntities()
+				if e == e0 {
+					e1.AddComponent(NewComponentA(1))
+				}
+			})
+			So(visited, ShouldResemble, []Entity{e0, e2})
+			So(observer.CollectedEntities(), ShouldBeEmpty)
+		})
+
+		Convey("It keeps entities collected while visiting", func() {
+			observer.ForEach(func(e Entity) {
+				if e == e0 {
+					e1.AddComponent(NewComponentA(1))
+				}
+			})
+			So(observer.CollectedEntities(), ShouldResemble, []Entity{e0, e1, e2})
+		})
+	})
+
+	Convey("Given an observer of a group of 10k entities", t, func() {
+		var skipped Component
+		g := NewGroup(&skipMatcher{skipped: &skipped})
+		entities := make([]Entity, 10000)
+		for i := range entities {
+			entities[i] = NewEntity(i)
+			entities[i].AddComponent(NewComponentA(i))
+		}
+		observer := NewGroupObserver(g, ObserverEntityAddedOrRemoved)
+		count := 0
+		visit := func(e Entity) { count++ }
+		frame := func() {
+			for _, e := range entities {
+				g.HandleEntity(e)
+			}
+			observer.ForEach(visit)
+			observer.ClearCollectedEntities()
+			skipped = entities[len(entities)/2].GetComponent(ComponentA)
+			g.HandleEntity(entities[len(entities)/2])
+			skipped = nil
+			observer.ForEach(visit)
+			observer.ClearCollectedEntities()
+		}
+		frame()
+
+		Convey("Collecting and visiting doesn't allocate", func() {
+			So(testing.AllocsPerRun(10, frame), ShouldEqual, 0)
+		})
+	})
+}
+
+func BenchmarkGroupObserverForEach(b *testing.B) {
+	pool := NewContext(0)
+	group := pool.Group(AllOf(ComponentA))
+	observer := NewGroupObserver(group, ObserverEntityAdded)
+	for i := 0; i < 10000; i++ {
+		pool.CreateEntity(NewComponentA(i))
+	}
+	count := 0
+	visit := func(e Entity) { count++ }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		observer.ForEach(visit)
+	}
+}
@@ -1,6 +1,7 @@
 package entitas
 
 import (
+	"errors"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -78,7 +79,7 @@ func TestGroup(t *testing.T) {
 						})
 
 						Convey("It should return the old and new entity", func() {
-							So(g.Entities(), ShouldResemble, []Entity{e3, e2})
+							So(g.Entities(), ShouldResemble, []Entity{e2, e3})
 						})
 					})
 				})
@@ -123,6 +124,102 @@ func TestGroup(t *testing.T) {
 	})
 }
 
+func TestGroupOrder(t *testing.T) {
+	Convey("Given a group", t, func() {
+		g := NewGroup(AllOf(ComponentA))
+		es := make([]Entity, 5)
+		for i := range es {
+			es[i] = NewEntity(i)
+			es[i].AddComponent(NewComponentA(len(es) - i))
+		}
+
+		Convey("It returns entities ordered by ID", func() {
+			for _, i := range []int{3, 0, 4, 1, 2} {
+				g.HandleEntity(es[i])
+			}
+			So(g.Entities(), ShouldResemble, es)
+
+			es[2].RemoveComponent(ComponentA)
+			g.HandleEntity(es[2])
+			So(g.Entities(), ShouldResemble, []Entity{es[0], es[1], es[3], es[4]})
+		})
+
+		Convey("It doesn't modify entities returned before", func() {
+			g.HandleEntity(es[1])
+			g.HandleEntity(es[3])
+			before := g.Entities()
+			g.HandleEntity(es[2])
+			es[1].RemoveComponent(ComponentA)
+			g.HandleEntity(es[1])
+			So(before, ShouldResemble, []Entity{es[1], es[3]})
+			So(g.Entities(), ShouldResemble, []Entity{es[2], es[3]})
+		})
+
+		Convey("It changes entities in place and copies them only when asked for", func() {
+			g.HandleEntity(es[1])
+			g.HandleEntity(es[3])
+			before := g.Entities()
+			So(&g.Entities()[0], ShouldEqual, &before[0])
+
+			sorted := &g.(*group).sorted[0]
+			es[3].RemoveComponent(ComponentA)
+			g.HandleEntity(es[3])
+			g.HandleEntity(es[2])
+			So(&g.(*group).sorted[0], ShouldEqual, sorted)
+			So(before, ShouldResemble, []Entity{es[1], es[3]})
+			So(g.Entities(), ShouldResemble, []Entity{es[1], es[2]})
+		})
+	})
+
+	Convey("Given a sorted group", t, func() {
+		byValue := func(a, b Entity) int {
+			ca, _ := a.Component(ComponentA)
+			cb, _ := b.Component(ComponentA)
+			return ca.(*componentA).value - cb.(*componentA).value
+		}
+		p := NewContext(0)
+		e0 := p.CreateEntity(NewComponentA(3))
+		e1 := p.CreateEntity(NewComponentA(1))
+		e2 := p.CreateEntity(NewComponentA(2))
+		g := p.SortedGroup(AllOf(ComponentA), byValue)
+
+		Convey("It orders entities with the comparator", func() {
+			So(g.Entities(), ShouldResemble, []Entity{e1, e2, e0})
+		})
+
+		Convey("It breaks ties by ID", func() {
+			e3 := p.CreateEntity(NewComponentA(2))
+			So(g.Entities(), ShouldResemble, []Entity{e1, e2, e3, e0})
+		})
+
+		Convey("It moves entities when their components are replaced", func() {
+			e0.ReplaceComponent(NewComponentA(0))
+			So(g.Entities(), ShouldResemble, []Entity{e0, e1, e2})
+			e1.ReplaceComponent(NewComponentA(5))
+			So(g.Entities(), ShouldResemble, []Entity{e0, e2, e1})
+		})
+
+		Convey("It removes entities", func() {
+			e2.RemoveComponent(ComponentA)
+			p.DestroyEntity(e1)
+			So(g.Entities(), ShouldResemble, []Entity{e0})
+		})
+
+		Convey("It is not the cached group of the matcher", func() {
+			So(g, ShouldNotEqual, p.Group(AllOf(ComponentA)))
+			So(p.Group(AllOf(ComponentA)).Entities(), ShouldResemble, []Entity{e0, e1, e2})
+		})
+
+		Convey("It stops updating after being released", func() {
+			So(p.ReleaseGroup(g), ShouldBeTrue)
+			So(p.ReleaseGroup(g), ShouldBeFalse)
+			p.CreateEntity(NewComponentA(0))
+			p.DestroyEntity(e0)
+			So(g.Entities(), ShouldResemble, []Entity{e1, e2, e0})
+		})
+	})
+}
+
 func BenchmarkGroupAddEntity(b *testing.B) {
 	BenchGroup(b, func(e *entity, g Group) {
 		e.id = EntityID(1000)
@@ -163,7 +260,7 @@ func BenchGroup(b *testing.B, f func(*entity, Group)) {
 	e := &entity{
 		id:         0,
 		components: make(map[ComponentType]Component),
-		callbacks:  make(map[ComponentEvent][]ComponentCallback),
+		callbacks:  make(map[ComponentEvent]callbackList[ComponentCallback]),
 	}
 	Entity(e).AddComponent(c1, c2)
 
@@ -176,3 +273,293 @@ func BenchGroup(b *testing.B, f func(*entity, Group)) {
 		f(e, g)
 	}
 }
+
+func TestGroupEntityUpdated(t *testing.T) {
+	Convey("Given a group of a pool", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		old := NewComponentA(1)
+		e := p.CreateEntity(old)
+
+		var events []GroupEvent
+		for _, ev := range []GroupEvent{EntityAdded, EntityRemoved, EntityUpdated} {
+			ev := ev
+			g.AddCallback(ev, func(Group, Entity) { events = append(events, ev) })
+		}
+		var gotPrev, gotCur Component
+		g.AddUpdateCallback(func(_ Group, _ Entity, prev, cur Component) {
+			gotPrev, gotCur = prev, cur
+		})
+
+		Convey("Replacing a component fires EntityUpdated with both values", func() {
+			cur := NewComponentA(2)
+			e.ReplaceComponent(cur)
+			So(events, ShouldResemble, []GroupEvent{EntityRemoved, EntityAdded, EntityUpdated})
+			So(gotPrev, ShouldEqual, old)
+			So(gotCur, ShouldEqual, cur)
+		})
+
+		Convey("Replacing a component the group doesn't watch fires nothing", func() {
+			e.ReplaceComponent(NewComponentB(1))
+			e.ReplaceComponent(NewComponentB(2))
+			So(events, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGroupValidator(t *testing.T) {
+	Convey("Given a group of a pool with a validator", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		errTooBig := errors.New("too big")
+		sub := g.AddValidator(func(_ Group, e Entity) error {
+			if e.GetComponent(ComponentA).(*componentA).value > 10 {
+				return errTooBig
+			}
+			return nil
+		})
+		e := p.CreateEntity(NewComponentA(1))
+
+		Convey("The validator sees the entity as it would be after the change", func() {
+			So(e.ReplaceComponent(NewComponentA(11)), ShouldEqual, errTooBig)
+			So(e.GetComponent(ComponentA).(*componentA).value, ShouldEqual, 1)
+			So(e.ReplaceComponent(NewComponentA(2)), ShouldBeNil)
+		})
+
+		Convey("Entities the group doesn't match aren't validated", func() {
+			So(p.CreateEntity(NewComponentB(11)), ShouldNotBeNil)
+		})
+
+		Convey("Removing the validator stops validation", func() {
+			So(g.RemoveCallback(sub), ShouldBeTrue)
+			So(e.ReplaceComponent(NewComponentA(11)), ShouldBeNil)
+		})
+	})
+}
+
+func TestGroupIteration(t *testing.T) {
+	Convey("Given a group of a pool", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		e0 := p.CreateEntity(NewComponentA(0))
+		e1 := p.CreateEntity(NewComponentA(1))
+		e2 := p.CreateEntity(NewComponentA(2))
+
+		Convey("ForEach visits entities in order", func() {
+			var visited []Entity
+			g.ForEach(func(e Entity) { visited = append(visited, e) })
+			So(visited, ShouldResemble, []Entity{e0, e1, e2})
+		})
+
+		Convey("ForEach walks the entities at the time it was called", func() {
+			var visited []Entity
+			g.ForEach(func(e Entity) {
+				visited = append(visited, e)
+				if e == e0 {
+					e1.RemoveComponent(ComponentA)
+					p.CreateEntity(NewComponentA(3))
+				}
+			})
+			So(visited, ShouldResemble, []Entity{e0, e1, e2})
+			So(len(g.Entities()), ShouldEqual, 3)
+			So(g.Entities(), ShouldNotContain, e1)
+		})
+
+		Convey("Iter walks entities with a cursor", func() {
+			var visited []Entity
+			it := g.Iter()
+			So(it.Len(), ShouldEqual, 3)
+			for it.Next() {
+				visited = append(visited, it.Entity())
+			}
+			So(visited, ShouldResemble, []Entity{e0, e1, e2})
+			So(it.Next(), ShouldBeFalse)
+		})
+
+		Convey("Iter walks the entities at the time it was called", func() {
+			var visited []Entity
+			for it := g.Iter(); it.Next(); {
+				visited = append(visited, it.Entity())
+				if it.Entity() == e0 {
+					e1.RemoveComponent(ComponentA)
+				}
+			}
+			So(visited, ShouldResemble, []Entity{e0, e1, e2})
+			So(g.Entities(), ShouldResemble, []Entity{e0, e2})
+		})
+
+		Convey("Breaking out of Iter copies the entities at most once", func() {
+			for it := g.Iter(); it.Next(); {
+				break
+			}
+			e1.RemoveComponent(ComponentA)
+			sorted := &g.(*group).sorted[0]
+			e1.AddComponent(NewComponentA(1))
+			So(&g.(*group).sorted[0], ShouldEqual, sorted)
+			So(g.Entities(), ShouldResemble, []Entity{e0, e1, e2})
+		})
+
+		Convey("Entity components can be walked in type order", func() {
+			e := p.CreateEntity(NewComponentC(), NewComponentA(4), NewComponentB(4.4))
+			var types []ComponentType
+			e.ForEachComponent(func(c Component) { types = append(types, c.Type()) })
+			So(types, ShouldResemble, []ComponentType{ComponentA, ComponentB, ComponentC})
+			e.RemoveComponent(ComponentB)
+			types = nil
+			e.ForEachComponent(func(c Component) { types = append(types, c.Type()) })
+			So(types, ShouldResemble, []ComponentType{ComponentA, ComponentC})
+		})
+
+		Convey("Entity components can be changed while they are walked", func() {
+			e := p.CreateEntity(NewComponentA(4), NewComponentB(4.4), NewComponentC())
+			var outer, inner []ComponentType
+			e.ForEachComponent(func(c Component) {
+				outer = append(outer, c.Type())
+				if c.Type() == ComponentA {
+					e.RemoveComponent(ComponentB)
+					e.ForEachComponent(func(c Component) { inner = append(inner, c.Type()) })
+					e.AddComponent(NewComponentB(1))
+				}
+			})
+			So(outer, ShouldResemble, []ComponentType{ComponentA, ComponentB, ComponentC})
+			So(inner, ShouldResemble, []ComponentType{ComponentA, ComponentC})
+			So(e.GetComponent(ComponentB).(*componentB).value, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestGroupIterationAllocs(t *testing.T) {
+	Convey("Given a group of 10k entities", t, func() {
+		_, g := newBenchGroup(10000)
+		sum := 0
+		visit := func(e Entity) { sum += int(e.ID()) }
+
+		Convey("ForEach doesn't allocate", func() {
+			So(testing.AllocsPerRun(10, func() { g.ForEach(visit) }), ShouldEqual, 0)
+		})
+
+		Convey("Iter doesn't allocate", func() {
+			So(testing.AllocsPerRun(10, func() {
+				for it := g.Iter(); it.Next(); {
+					visit(it.Entity())
+				}
+			}), ShouldEqual, 0)
+		})
+
+		Convey("Changing the group between frames doesn't copy it", func() {
+			var skipped Component
+			g := NewGroup(&skipMatcher{skipped: &skipped})
+			for i := 0; i < 10000; i++ {
+				e := NewEntity(i)
+				e.AddComponent(NewComponentA(i))
+				g.HandleEntity(e)
+			}
+			e := g.Entities()[5000]
+			skipped = e.GetComponent(ComponentA)
+			g.HandleEntity(e)
+			g.ForEach(visit)
+			So(testing.AllocsPerRun(10, func() {
+				skipped = nil
+				g.HandleEntity(e)
+				g.ForEach(visit)
+				skipped = e.GetComponent(ComponentA)
+				g.HandleEntity(e)
+				g.ForEach(visit)
+			}), ShouldEqual, 0)
+		})
+
+		Convey("Changing the group between Iter loops doesn't allocate", func() {
+			var skipped Component
+			g := NewGroup(&skipMatcher{skipped: &skipped})
+			for i := 0; i < 10000; i++ {
+				e := NewEntity(i)
+				e.AddComponent(NewComponentA(i))
+				g.HandleEntity(e)
+			}
+			e := g.Entities()[5000]
+			iterate := func() {
+				for it := g.Iter(); it.Next(); {
+					visit(it.Entity())
+				}
+			}
+			skipped = e.GetComponent(ComponentA)
+			g.HandleEntity(e)
+			iterate()
+			So(testing.AllocsPerRun(10, func() {
+				skipped = nil
+				g.HandleEntity(e)
+				iterate()
+				skipped = e.GetComponent(ComponentA)
+				g.HandleEntity(e)
+				iterate()
+			}), ShouldEqual, 0)
+		})
+
+		Convey("Walking components doesn't allocate", func() {
+			e := g.Entities()[0]
+			visitComponent := func(c Component) { sum += int(c.Type()) }
+			e.ForEachComponent(visitComponent)
+			So(testing.AllocsPerRun(10, func() { e.ForEachComponent(visitComponent) }), ShouldEqual, 0)
+		})
+	})
+}
+
+// skipMatcher 匹配ComponentA不是*skipped的entity. Where要调用变长参数的HasComponent, 会分配内存,
+// 测试group本身不分配内存时用它.
+type skipMatcher struct {
+	skipped *Component
+}
+
+func (m *skipMatcher) Matches(e Entity) bool {
+	c, err := e.Component(ComponentA)
+	return err == nil && c != *m.skipped
+}
+
+func (m *skipMatcher) Hash() MatcherHash               { return 0 }
+func (m *skipMatcher) ComponentTypes() []ComponentType { return []ComponentType{ComponentA} }
+func (m *skipMatcher) Equals(o Matcher) bool           { return m == o }
+func (m *skipMatcher) String() string                  { return "skip" }
+
+func newBenchGroup(n int) (Context, Group) {
+	p := NewContext(0)
+	g := p.Group(AllOf(ComponentA))
+	for i := 0; i < n; i++ {
+		p.CreateEntity(NewComponentA(i), NewComponentB(float32(i)))
+	}
+	return p, g
+}
+
+func BenchmarkGroupForEach(b *testing.B) {
+	_, g := newBenchGroup(10000)
+	sum := 0
+	visit := func(e Entity) { sum += e.GetComponent(ComponentA).(*componentA).value }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g.ForEach(visit)
+	}
+}
+
+func BenchmarkGroupIter(b *testing.B) {
+	_, g := newBenchGroup(10000)
+	sum := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for it := g.Iter(); it.Next(); {
+			sum += it.Entity().GetComponent(ComponentA).(*componentA).value
+		}
+	}
+}
+
+func BenchmarkEntityForEachComponent(b *testing.B) {
+	_, g := newBenchGroup(1)
+	e := g.Entities()[0]
+	count := 0
+	visit := func(c Component) { count++ }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		e.ForEachComponent(visit)
+	}
+}
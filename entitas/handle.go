@@ -0,0 +1,51 @@
+package entitas
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// EntityHandle 是对entity的弱引用, 低32位是EntityID, 高32位是generation.
+// entity被销毁之后generation会失效, 即使EntityID被复用, 旧的handle也不会指向新的entity.
+type EntityHandle uint64
+
+// InvalidEntityHandle 不指向任何entity.
+const InvalidEntityHandle EntityHandle = 0
+
+func NewEntityHandle(id EntityID, generation uint32) EntityHandle {
+	return EntityHandle(uint64(generation)<<32 | uint64(uint32(id)))
+}
+
+func (h EntityHandle) ID() EntityID {
+	return EntityID(uint32(h))
+}
+
+func (h EntityHandle) Generation() uint32 {
+	return uint32(h >> 32)
+}
+
+func (h EntityHandle) String() string {
+	return fmt.Sprintf("Handle_%d.%d", h.ID(), h.Generation())
+}
+
+// entityGeneration 记录entity对象当前是第几次被分配出去, 和Context.Handle里的generation一致.
+// 不需要Context就能得到entity的handle, 命令缓冲在记录命令时用它.
+type entityGeneration struct {
+	gen atomic.Uint32
+}
+
+func (g *entityGeneration) generation() uint32 {
+	return g.gen.Load()
+}
+
+func (g *entityGeneration) setGeneration(gen uint32) {
+	g.gen.Store(gen)
+}
+
+// handleOf 返回e当前的handle, 不是由Context创建的entity返回InvalidEntityHandle.
+func handleOf(e Entity) EntityHandle {
+	if ce, ok := e.(contextEntity); ok && ce.generation() > 0 {
+		return NewEntityHandle(e.ID(), ce.generation())
+	}
+	return InvalidEntityHandle
+}
@@ -0,0 +1,60 @@
+package entitas
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEntityHandle(t *testing.T) {
+	Convey("Given a handle", t, func() {
+		h := NewEntityHandle(42, 7)
+
+		Convey("It packs id and generation", func() {
+			So(h.ID(), ShouldEqual, 42)
+			So(h.Generation(), ShouldEqual, 7)
+			So(h.String(), ShouldEqual, "Handle_42.7")
+		})
+	})
+
+	Convey("Given a pool with an entity", t, func() {
+		p := NewContext(0)
+		e := p.CreateEntity(NewComponentA(1))
+		h := p.Handle(e)
+
+		Convey("It resolves the handle while the entity is alive", func() {
+			So(h, ShouldNotEqual, InvalidEntityHandle)
+			So(p.IsAlive(h), ShouldBeTrue)
+			resolved, ok := p.Resolve(h)
+			So(ok, ShouldBeTrue)
+			So(resolved, ShouldEqual, e)
+		})
+
+		Convey("It invalidates the handle when the entity is destroyed", func() {
+			p.DestroyEntity(e)
+			So(p.IsAlive(h), ShouldBeFalse)
+			resolved, ok := p.Resolve(h)
+			So(ok, ShouldBeFalse)
+			So(resolved, ShouldBeNil)
+		})
+
+		Convey("It doesn't resolve a stale handle to a recycled entity", func() {
+			p.DestroyEntity(e)
+			recycled := p.CreateEntity()
+			So(recycled.ID(), ShouldEqual, e.ID())
+			So(p.IsAlive(h), ShouldBeFalse)
+			So(p.IsAlive(p.Handle(recycled)), ShouldBeTrue)
+			So(p.Handle(recycled).Generation(), ShouldEqual, h.Generation()+1)
+		})
+
+		Convey("It invalidates handles when all entities are destroyed", func() {
+			p.DestroyAllEntities()
+			So(p.IsAlive(h), ShouldBeFalse)
+		})
+
+		Convey("It returns an invalid handle for foreign entities", func() {
+			So(p.Handle(NewEntity(-1)), ShouldEqual, InvalidEntityHandle)
+			So(p.IsAlive(InvalidEntityHandle), ShouldBeFalse)
+		})
+	})
+}
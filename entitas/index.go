@@ -0,0 +1,242 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var ErrDuplicatePrimaryKey = errors.New("duplicate primary key")
+
+// KeyFunc 从entity的组件里取出索引用的key.
+// 用到的组件必须在group的matcher里, 否则替换组件时group收不到事件, 索引不会更新.
+type KeyFunc[K comparable] func(e Entity) K
+
+// --- PrimaryEntityIndex -----------------------------------------------------
+
+// PrimaryEntityIndex 是一对一的索引, 每个key最多对应一个entity.
+// 索引通过group的EntityAdded/EntityRemoved事件更新, 替换组件时group会先后触发这两个事件.
+// 直接修改组件内部的字段不会触发事件, 需要改key时要用ReplaceComponent.
+type PrimaryEntityIndex[K comparable] struct {
+	mu       sync.RWMutex
+	group    Group
+	subs     []Subscription
+	key      KeyFunc[K]
+	entities map[K]Entity
+	keys     map[EntityID]K // entity加入索引时的key, 组件被替换之后只能从这里找到旧的key
+}
+
+// NewPrimaryEntityIndex 为group创建主键索引, group里已有的entity会立即加入索引.
+// 已有的entity里存在重复的key时返回ErrDuplicatePrimaryKey.
+// 之后group属于Context时, 会产生重复key的AddComponent/ReplaceComponent在修改entity之前返回ErrDuplicatePrimaryKey,
+// CreateEntity会在创建entity之前panic, 见Group.AddValidator.
+func NewPrimaryEntityIndex[K comparable](g Group, key KeyFunc[K]) (*PrimaryEntityIndex[K], error) {
+	index := &PrimaryEntityIndex[K]{
+		group:    g,
+		key:      key,
+		entities: make(map[K]Entity),
+		keys:     make(map[EntityID]K),
+	}
+	for _, e := range g.Entities() {
+		if err := index.add(e); err != nil {
+			return nil, err
+		}
+	}
+	index.subs = []Subscription{
+		g.AddValidator(func(g Group, e Entity) error {
+			return index.validate(e)
+		}),
+		// 检查函数已经拒绝了重复的key, 绕过Context直接修改group时add返回的错误只能忽略, 这个entity不在索引里.
+		subscribe(g, EntityAdded, func(g Group, e Entity) {
+			_ = index.add(e)
+		}),
+		subscribe(g, EntityRemoved, func(g Group, e Entity) {
+			index.remove(e)
+		}),
+	}
+	return index, nil
+}
+
+// subscribe 注册索引的回调. 开启WithLocking时普通的回调推迟到解锁之后,
+// 索引要在锁里更新, 下一次检查才能看到这次修改.
+func subscribe(g Group, ev GroupEvent, c GroupCallback) Subscription {
+	if g, ok := g.(*group); ok {
+		return g.addSyncCallback(ev, c)
+	}
+	return g.AddCallback(ev, c)
+}
+
+// Dispose 从group上删除回调, 之后索引不再更新.
+func (i *PrimaryEntityIndex[K]) Dispose() {
+	for _, sub := range i.subs {
+		i.group.RemoveCallback(sub)
+	}
+	i.subs = nil
+}
+
+// GetEntity 返回key对应的entity.
+func (i *PrimaryEntityIndex[K]) GetEntity(key K) (Entity, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	e, ok := i.entities[key]
+	return e, ok
+}
+
+// HasEntity 判断是否有entity的key等于参数key.
+func (i *PrimaryEntityIndex[K]) HasEntity(key K) bool {
+	_, ok := i.GetEntity(key)
+	return ok
+}
+
+// Count 返回索引里entity的数量.
+func (i *PrimaryEntityIndex[K]) Count() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entities)
+}
+
+// validate 检查e的key是否已经被别的entity使用.
+func (i *PrimaryEntityIndex[K]) validate(e Entity) error {
+	key := i.key(e)
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if other, ok := i.entities[key]; ok && other.ID() != e.ID() {
+		return fmt.Errorf("%w: %v is used by %v and %v", ErrDuplicatePrimaryKey, key, other, e)
+	}
+	return nil
+}
+
+// add 把e加入索引. key重复时e的旧key也被删除, 索引里不会留下过时的key.
+func (i *PrimaryEntityIndex[K]) add(e Entity) error {
+	key := i.key(e)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if old, ok := i.keys[e.ID()]; ok {
+		delete(i.keys, e.ID())
+		delete(i.entities, old)
+	}
+	if other, ok := i.entities[key]; ok && other.ID() != e.ID() {
+		return fmt.Errorf("%w: %v is used by %v and %v", ErrDuplicatePrimaryKey, key, other, e)
+	}
+	i.entities[key] = e
+	i.keys[e.ID()] = key
+	return nil
+}
+
+func (i *PrimaryEntityIndex[K]) remove(e Entity) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if key, ok := i.keys[e.ID()]; ok {
+		delete(i.keys, e.ID())
+		delete(i.entities, key)
+	}
+}
+
+// --- EntityIndex ------------------------------------------------------------
+
+// EntityIndex 是一对多的索引, 一个key可以对应多个entity. 更新方式和PrimaryEntityIndex一样.
+type EntityIndex[K comparable] struct {
+	mu       sync.RWMutex
+	group    Group
+	subs     []Subscription
+	key      KeyFunc[K]
+	entities map[K]map[EntityID]Entity
+	keys     map[EntityID]K
+}
+
+// NewEntityIndex 为group创建索引, group里已有的entity会立即加入索引.
+func NewEntityIndex[K comparable](g Group, key KeyFunc[K]) *EntityIndex[K] {
+	index := &EntityIndex[K]{
+		group:    g,
+		key:      key,
+		entities: make(map[K]map[EntityID]Entity),
+		keys:     make(map[EntityID]K),
+	}
+	for _, e := range g.Entities() {
+		index.add(e)
+	}
+	index.subs = []Subscription{
+		subscribe(g, EntityAdded, func(g Group, e Entity) {
+			index.add(e)
+		}),
+		subscribe(g, EntityRemoved, func(g Group, e Entity) {
+			index.remove(e)
+		}),
+	}
+	return index
+}
+
+// Dispose 从group上删除回调, 之后索引不再更新.
+func (i *EntityIndex[K]) Dispose() {
+	for _, sub := range i.subs {
+		i.group.RemoveCallback(sub)
+	}
+	i.subs = nil
+}
+
+// GetEntities 返回key对应的所有entity, 按ID排序.
+func (i *EntityIndex[K]) GetEntities(key K) []Entity {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	bucket := i.entities[key]
+	entities := make([]Entity, 0, len(bucket))
+	for _, e := range bucket {
+		entities = append(entities, e)
+	}
+	sort.Slice(entities, func(a, b int) bool {
+		return entities[a].ID() < entities[b].ID()
+	})
+	return entities
+}
+
+// Count 返回key对应的entity数量.
+func (i *EntityIndex[K]) Count(key K) int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entities[key])
+}
+
+// Keys 返回索引里所有的key, 顺序不固定.
+func (i *EntityIndex[K]) Keys() []K {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	keys := make([]K, 0, len(i.entities))
+	for key := range i.entities {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (i *EntityIndex[K]) add(e Entity) {
+	key := i.key(e)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if old, ok := i.keys[e.ID()]; ok {
+		i.removeKey(old, e.ID())
+	}
+	bucket, ok := i.entities[key]
+	if !ok {
+		bucket = make(map[EntityID]Entity)
+		i.entities[key] = bucket
+	}
+	bucket[e.ID()] = e
+	i.keys[e.ID()] = key
+}
+
+func (i *EntityIndex[K]) remove(e Entity) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if key, ok := i.keys[e.ID()]; ok {
+		delete(i.keys, e.ID())
+		i.removeKey(key, e.ID())
+	}
+}
+
+func (i *EntityIndex[K]) removeKey(key K, id EntityID) {
+	bucket := i.entities[key]
+	delete(bucket, id)
+	if len(bucket) == 0 {
+		delete(i.entities, key)
+	}
+}
@@ -0,0 +1,133 @@
+package entitas
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func valueOfA(e Entity) int {
+	return e.GetComponent(ComponentA).(*componentA).value
+}
+
+func TestPrimaryEntityIndex(t *testing.T) {
+	Convey("Given a primary index on a group", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		e1 := p.CreateEntity(NewComponentA(1))
+		index, err := NewPrimaryEntityIndex(g, valueOfA)
+		So(err, ShouldBeNil)
+
+		Convey("It indexes existing entities", func() {
+			e, ok := index.GetEntity(1)
+			So(ok, ShouldBeTrue)
+			So(e, ShouldEqual, e1)
+		})
+
+		Convey("It indexes new entities", func() {
+			e2 := p.CreateEntity(NewComponentA(2))
+			e, ok := index.GetEntity(2)
+			So(ok, ShouldBeTrue)
+			So(e, ShouldEqual, e2)
+			So(index.Count(), ShouldEqual, 2)
+		})
+
+		Convey("It follows replaced components", func() {
+			e1.ReplaceComponent(NewComponentA(3))
+			So(index.HasEntity(1), ShouldBeFalse)
+			e, ok := index.GetEntity(3)
+			So(ok, ShouldBeTrue)
+			So(e, ShouldEqual, e1)
+		})
+
+		Convey("It forgets removed and destroyed entities", func() {
+			e2 := p.CreateEntity(NewComponentA(2))
+			e1.RemoveComponent(ComponentA)
+			p.DestroyEntity(e2)
+			So(index.HasEntity(1), ShouldBeFalse)
+			So(index.HasEntity(2), ShouldBeFalse)
+			So(index.Count(), ShouldEqual, 0)
+		})
+
+		Convey("Creating an entity with a duplicate key panics before the entity is created", func() {
+			So(func() { p.CreateEntity(NewComponentA(1)) }, ShouldPanic)
+			So(p.Count(), ShouldEqual, 1)
+			So(g.Entities(), ShouldResemble, []Entity{e1})
+			So(index.Count(), ShouldEqual, 1)
+		})
+
+		Convey("Replacing a key with an existing key fails without changing anything", func() {
+			e2 := p.CreateEntity(NewComponentA(2))
+			err := e2.ReplaceComponent(NewComponentA(1))
+			So(errors.Is(err, ErrDuplicatePrimaryKey), ShouldBeTrue)
+			So(valueOfA(e2), ShouldEqual, 2)
+			e, _ := index.GetEntity(1)
+			So(e, ShouldEqual, e1)
+			e, _ = index.GetEntity(2)
+			So(e, ShouldEqual, e2)
+			So(index.Count(), ShouldEqual, 2)
+		})
+
+		Convey("Adding a duplicate key fails without changing anything", func() {
+			e2 := p.CreateEntity(NewComponentB(2))
+			err := e2.AddComponent(NewComponentA(1))
+			So(errors.Is(err, ErrDuplicatePrimaryKey), ShouldBeTrue)
+			So(e2.HasComponent(ComponentA), ShouldBeFalse)
+			So(g.ContainsEntity(e2), ShouldBeFalse)
+			So(index.Count(), ShouldEqual, 1)
+		})
+
+		Convey("It rejects duplicates with locking too", func() {
+			p := NewContext(0, WithLocking())
+			g := p.Group(AllOf(ComponentA))
+			index, _ := NewPrimaryEntityIndex(g, valueOfA)
+			p.CreateEntity(NewComponentA(1))
+			e2 := p.CreateEntity(NewComponentA(2))
+			So(errors.Is(e2.ReplaceComponent(NewComponentA(1)), ErrDuplicatePrimaryKey), ShouldBeTrue)
+			So(index.Count(), ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given a group with duplicate keys", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		p.CreateEntity(NewComponentA(1))
+		p.CreateEntity(NewComponentA(1))
+
+		Convey("Creating a primary index fails", func() {
+			index, err := NewPrimaryEntityIndex(g, valueOfA)
+			So(index, ShouldBeNil)
+			So(errors.Is(err, ErrDuplicatePrimaryKey), ShouldBeTrue)
+		})
+	})
+}
+
+func TestEntityIndex(t *testing.T) {
+	Convey("Given an index on a group", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		e1 := p.CreateEntity(NewComponentA(7))
+		e2 := p.CreateEntity(NewComponentA(7))
+		index := NewEntityIndex(g, valueOfA)
+
+		Convey("It returns all entities with the key", func() {
+			So(index.GetEntities(7), ShouldResemble, []Entity{e1, e2})
+			So(index.Count(7), ShouldEqual, 2)
+			So(index.GetEntities(8), ShouldBeEmpty)
+		})
+
+		Convey("It follows replaced components", func() {
+			e1.ReplaceComponent(NewComponentA(8))
+			So(index.GetEntities(7), ShouldResemble, []Entity{e2})
+			So(index.GetEntities(8), ShouldResemble, []Entity{e1})
+		})
+
+		Convey("It forgets destroyed entities and empty keys", func() {
+			e3 := p.CreateEntity(NewComponentA(9))
+			p.DestroyEntity(e3)
+			So(index.Count(9), ShouldEqual, 0)
+			So(index.Keys(), ShouldResemble, []int{7})
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package entitas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+const jsonVersion = 1
+
+// jsonDocument 是SaveJSON写出的文档. entity按ID排序, 组件以注册的名字为key,
+// encoding/json会按key排序, 所以同样的内容总是得到同样的文本, 方便diff.
+type jsonDocument struct {
+	Version  int          `json:"version"`
+	Entities []jsonEntity `json:"entities"`
+}
+
+type jsonEntity struct {
+	ID         EntityID                   `json:"id"`
+	Components map[string]json.RawMessage `json:"components"`
+}
+
+// SaveJSON 把ctx里所有的entity写成JSON. 组件必须在DefaultRegistry里注册过,
+// 用encoding/json编码, 所以只有导出的字段会被保存.
+// 组件里有未导出的字段时返回错误而不是悄悄丢掉它们, 这样的组件要实现json.Marshaler和json.Unmarshaler.
+func SaveJSON(ctx Context, w io.Writer) error {
+	records := capture(ctx)
+	doc := jsonDocument{Version: jsonVersion, Entities: make([]jsonEntity, len(records))}
+	for i, r := range records {
+		entity := jsonEntity{ID: r.ID, Components: make(map[string]json.RawMessage, len(r.Components))}
+		for _, c := range r.Components {
+			info, ok := DefaultRegistry.Info(c.Type())
+			if !ok {
+				return fmt.Errorf("%w: %d on entity %d", ErrComponentNotRegistered, c.Type(), r.ID)
+			}
+			if err := checkJSONFields(reflect.TypeOf(c)); err != nil {
+				return fmt.Errorf("%w on entity %d", err, r.ID)
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				return fmt.Errorf("entitas: encode %s on entity %d: %w", info.Name, r.ID, err)
+			}
+			entity.Components[info.Name] = data
+		}
+		doc.Entities[i] = entity
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// LoadJSON 读取SaveJSON写出的文档, 替换掉ctx里所有的entity. entity保留原来的ID.
+// 文档有错误时ctx不会被修改, entity没有通过唯一组件或者group的检查时ctx恢复成加载之前的样子, 见restore.
+func LoadJSON(ctx Context, r io.Reader) error {
+	var doc jsonDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("entitas: decode snapshot: %w", err)
+	}
+	if doc.Version != jsonVersion {
+		return fmt.Errorf("entitas: unsupported snapshot version %d", doc.Version)
+	}
+
+	records := make([]entityRecord, len(doc.Entities))
+	for i, entity := range doc.Entities {
+		record := entityRecord{ID: entity.ID}
+		for name, data := range entity.Components {
+			info, ok := DefaultRegistry.Lookup(name)
+			if !ok {
+				return fmt.Errorf("%w: %q on entity %d", ErrComponentNotRegistered, name, entity.ID)
+			}
+			c, err := decodeJSONComponent(info, data)
+			if err != nil {
+				return fmt.Errorf("entitas: decode %s on entity %d: %w", name, entity.ID, err)
+			}
+			record.Components = append(record.Components, c)
+		}
+		sort.Sort(ComponentsByType(record.Components))
+		records[i] = record
+	}
+	return restore(ctx, records)
+}
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+// checkJSONFields 检查encoding/json会不会丢掉t里的数据. 自己实现了json.Marshaler的类型不检查,
+// 标记了json:"-"的字段是故意不保存的, 嵌入的未导出结构体的导出字段会被encoding/json展开.
+func checkJSONFields(t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := checkJSONFields(f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if skip, err := skipField(t, i, "implement json.Marshaler"); skip || err != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			if err := checkJSONFields(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeJSONComponent(info ComponentInfo, data []byte) (Component, error) {
+	v := newComponentValue(info.GoType)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return componentFromValue(info.GoType, v), nil
+}
+
+// newComponentValue 为组件的Go类型分配一个可以被解码的指针.
+func newComponentValue(goType reflect.Type) reflect.Value {
+	if goType.Kind() == reflect.Ptr {
+		return reflect.New(goType.Elem())
+	}
+	return reflect.New(goType)
+}
+
+// componentFromValue 把newComponentValue分配的指针还原成组件.
+func componentFromValue(goType reflect.Type, v reflect.Value) Component {
+	if goType.Kind() == reflect.Ptr {
+		return v.Interface().(Component)
+	}
+	return v.Elem().Interface().(Component)
+}
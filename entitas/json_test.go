@@ -0,0 +1,140 @@
+package entitas
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type savedPosition struct {
+	X, Y float64
+}
+
+func (c *savedPosition) Type() ComponentType { return savedPositionType }
+
+type savedName struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func (c savedName) Type() ComponentType { return savedNameType }
+
+type savedSecret struct {
+	ComponentOf[*savedSecret]
+	key string
+}
+
+var (
+	savedPositionType = RegisterComponentAs[*savedPosition](110, "position")
+	savedNameType     = RegisterComponentAs[savedName](111, "name")
+	savedSecretType   = RegisterComponentAs[*savedSecret](117, "secret")
+)
+
+func TestJSON(t *testing.T) {
+	Convey("Given a pool with registered components", t, func() {
+		p := NewContext(0)
+		e0 := p.CreateEntity(&savedPosition{X: 1, Y: 2}, savedName{Name: "hero", Tags: []string{"player"}})
+		e1 := p.CreateEntity(&savedPosition{X: 3})
+		e2 := p.CreateEntity(savedName{Name: "chest"})
+		p.DestroyEntity(e1)
+
+		var buf bytes.Buffer
+		So(SaveJSON(p, &buf), ShouldBeNil)
+		saved := buf.String()
+
+		Convey("The document is stable", func() {
+			var again bytes.Buffer
+			So(SaveJSON(p, &again), ShouldBeNil)
+			So(again.String(), ShouldEqual, saved)
+			So(strings.Index(saved, `"id": 0`), ShouldBeLessThan, strings.Index(saved, `"id": 2`))
+			So(saved, ShouldContainSubstring, `"position": {`)
+			So(saved, ShouldContainSubstring, `"name": "hero"`)
+		})
+
+		Convey("Loading restores entities, IDs and groups", func() {
+			q := NewContext(0)
+			q.CreateEntity(&savedPosition{})
+			positions := q.Group(AllOf(savedPositionType))
+			names := q.Group(AllOf(savedNameType))
+
+			So(LoadJSON(q, strings.NewReader(saved)), ShouldBeNil)
+			So(q.Count(), ShouldEqual, 2)
+			So(len(positions.Entities()), ShouldEqual, 1)
+			So(len(names.Entities()), ShouldEqual, 2)
+
+			hero := positions.Entities()[0]
+			So(hero.ID(), ShouldEqual, e0.ID())
+			So(hero.GetComponent(savedPositionType), ShouldResemble, &savedPosition{X: 1, Y: 2})
+			So(hero.GetComponent(savedNameType), ShouldResemble, savedName{Name: "hero", Tags: []string{"player"}})
+
+			var reloaded bytes.Buffer
+			So(SaveJSON(q, &reloaded), ShouldBeNil)
+			So(reloaded.String(), ShouldEqual, saved)
+
+			Convey("New entities don't reuse loaded IDs", func() {
+				e := q.CreateEntity()
+				So(e.ID(), ShouldNotEqual, e0.ID())
+				So(e.ID(), ShouldNotEqual, e2.ID())
+			})
+		})
+
+		Convey("Saving unregistered components fails", func() {
+			p.CreateEntity(NewComponentA(1))
+			err := SaveJSON(p, &bytes.Buffer{})
+			So(errors.Is(err, ErrComponentNotRegistered), ShouldBeTrue)
+		})
+
+		Convey("Saving components with unexported fields fails", func() {
+			p.CreateEntity(&savedSecret{key: "42"})
+			err := SaveJSON(p, &bytes.Buffer{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unexported field")
+		})
+
+		Convey("Loading entities the pool rejects leaves the pool alone", func() {
+			h0, h2 := p.Handle(e0), p.Handle(e2)
+			names, err := NewPrimaryEntityIndex(p.Group(AllOf(savedNameType)), func(e Entity) string {
+				return e.GetComponent(savedNameType).(savedName).Name
+			})
+			So(err, ShouldBeNil)
+			unchanged := func() {
+				So(p.Count(), ShouldEqual, 2)
+				So(p.IsAlive(h0) && p.IsAlive(h2), ShouldBeTrue)
+				So(e0.GetComponent(savedPositionType), ShouldResemble, &savedPosition{X: 1, Y: 2})
+				hero, _ := names.GetEntity("hero")
+				So(hero, ShouldEqual, e0)
+				var again bytes.Buffer
+				So(SaveJSON(p, &again), ShouldBeNil)
+				So(again.String(), ShouldEqual, saved)
+			}
+
+			err = LoadJSON(p, strings.NewReader(`{"version": 1, "entities": [
+				{"id": 0, "components": {"name": {"name": "a"}}},
+				{"id": 5, "components": {"name": {"name": "a"}}}]}`))
+			So(errors.Is(err, ErrDuplicatePrimaryKey), ShouldBeTrue)
+			unchanged()
+
+			So(p.MarkUnique(savedPositionType), ShouldBeNil)
+			err = LoadJSON(p, strings.NewReader(`{"version": 1, "entities": [
+				{"id": 0, "components": {"position": {"X": 1}}},
+				{"id": 5, "components": {"position": {"X": 2}}}]}`))
+			So(errors.Is(err, ErrUniqueComponentExists), ShouldBeTrue)
+			unchanged()
+			So(p.CreateEntity().ID(), ShouldEqual, e1.ID())
+			So(p.CreateEntity().ID(), ShouldEqual, 3)
+		})
+
+		Convey("Loading a broken document leaves the pool alone", func() {
+			err := LoadJSON(p, strings.NewReader(`{"version": 1, "entities": [{"id": 0, "components": {"unknown": {}}}]}`))
+			So(errors.Is(err, ErrComponentNotRegistered), ShouldBeTrue)
+			So(p.Count(), ShouldEqual, 2)
+
+			err = LoadJSON(p, strings.NewReader(`{"version": 1, "entities": [{"id": 0}, {"id": 0}]}`))
+			So(errors.Is(err, ErrDuplicateEntityID), ShouldBeTrue)
+			So(p.Count(), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,315 @@
+package entitas
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock 是Loop使用的时间来源, 测试时换成FakeClock.
+type Clock interface {
+	Now() time.Time
+	// Sleep 等待d, ctx结束时提前返回ctx.Err().
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock 是使用系统时间的Clock, Loop默认使用它.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FakeClock 是手动推进的Clock. Sleep不会阻塞, 直接把时间推进d,
+// 所以Loop.Run在FakeClock上会尽快地一帧接一帧执行, 结果和真实时间下完全一样.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 把时间推进d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d > 0 {
+		c.Advance(d)
+	}
+	return nil
+}
+
+// DefaultStep 是Loop在没有设置固定步长时的帧间隔和单步执行的步长.
+const DefaultStep = time.Second / 60
+
+const defaultMaxSteps = 8
+
+// Loop 驱动World一帧一帧地运行. 每一帧先执行零次或多次World.Tick, 再调用一次World.Render.
+//
+// 设置了WithFixedStep时, 经过的时间(乘以时间缩放)累加起来, 每满一个步长执行一次Tick(step),
+// 剩下不足一步的时间用来计算Render的alpha. 一帧最多执行WithMaxSteps次Tick, 追不上的时间会被丢掉.
+// 没有设置时每一帧执行一次Tick, dt是上一帧到现在经过的时间乘以时间缩放, 最大不超过WithMaxDelta.
+//
+// 第一帧会先调用World.Initialize, World已经初始化过时不会重复调用.
+//
+// Pause, Resume, Step和SetTimeScale可以在别的goroutine里调用, 在下一帧生效.
+type Loop struct {
+	world    *World
+	clock    Clock
+	step     time.Duration // 固定步长, 0表示可变步长
+	maxSteps int
+	maxDelta time.Duration // 可变步长时dt的上限, 0表示不限制
+	interval time.Duration // Run的帧间隔
+
+	mu          sync.Mutex
+	last        time.Time // 上一帧的时间, 零值表示还没有执行过
+	accumulator time.Duration
+	scale       float64
+	paused      bool
+	pending     int // 暂停时通过Step请求执行的步数
+	alpha       float64
+	ticks       uint64
+}
+
+type LoopOption func(*Loop)
+
+// WithClock 设置Loop的时间来源.
+func WithClock(c Clock) LoopOption {
+	return func(l *Loop) {
+		l.clock = c
+	}
+}
+
+// WithFixedStep 使用固定步长step执行Tick. Run的帧间隔默认也是step.
+func WithFixedStep(step time.Duration) LoopOption {
+	return func(l *Loop) {
+		l.step = step
+	}
+}
+
+// WithMaxSteps 设置一帧最多追赶的固定步数, 默认是8. 小于1时按1处理.
+func WithMaxSteps(n int) LoopOption {
+	return func(l *Loop) {
+		l.maxSteps = n
+	}
+}
+
+// WithMaxDelta 设置可变步长时一次Tick的dt上限, 比如断点或者窗口被拖动之后不会传入一个很大的dt.
+// 超出的时间被丢掉. 默认不限制, 使用固定步长时不起作用, 那时由WithMaxSteps限制.
+func WithMaxDelta(d time.Duration) LoopOption {
+	return func(l *Loop) {
+		l.maxDelta = d
+	}
+}
+
+// WithFrameInterval 设置Run两帧之间的间隔, 默认是固定步长, 没有固定步长时是DefaultStep.
+func WithFrameInterval(d time.Duration) LoopOption {
+	return func(l *Loop) {
+		l.interval = d
+	}
+}
+
+func NewLoop(w *World, opts ...LoopOption) *Loop {
+	l := &Loop{
+		world:    w,
+		clock:    SystemClock,
+		maxSteps: defaultMaxSteps,
+		scale:    1,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.maxSteps < 1 {
+		l.maxSteps = 1
+	}
+	if l.interval <= 0 {
+		l.interval = l.stepLength()
+	}
+	return l
+}
+
+// Run 不停地执行Advance直到ctx结束, 每帧之间用Clock等待到下一帧的时间.
+// ctx结束时返回ctx.Err(), Tick出错时返回这个错误.
+func (l *Loop) Run(ctx context.Context) error {
+	next := l.clock.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := l.Advance(); err != nil {
+			return err
+		}
+		next = next.Add(l.interval)
+		if wait := next.Sub(l.clock.Now()); wait > 0 {
+			if err := l.clock.Sleep(ctx, wait); err != nil {
+				return err
+			}
+		} else {
+			// 落后太多时不再试图追上之前的帧
+			next = l.clock.Now()
+		}
+	}
+}
+
+// Advance 执行一帧. 第一帧只初始化World, 记录时间并渲染, 不执行Tick.
+func (l *Loop) Advance() error {
+	now := l.clock.Now()
+	l.mu.Lock()
+	first := l.last.IsZero()
+	if first {
+		l.last = now
+	}
+	l.mu.Unlock()
+	if first {
+		// 系统的Initialize可能会调用Pause这样的方法, 不能持有锁.
+		l.world.Initialize()
+	}
+
+	l.mu.Lock()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	var steps int
+	var dt time.Duration
+	switch {
+	case l.paused:
+		steps, l.pending = l.pending, 0
+		dt = l.stepLength()
+	case l.step > 0:
+		l.accumulator += l.scaled(elapsed)
+		steps = int(l.accumulator / l.step)
+		if steps > l.maxSteps {
+			steps = l.maxSteps
+			l.accumulator %= l.step
+		} else {
+			l.accumulator -= time.Duration(steps) * l.step
+		}
+		dt = l.step
+		l.alpha = float64(l.accumulator) / float64(l.step)
+	default:
+		dt = l.scaled(elapsed)
+		if l.maxDelta > 0 && dt > l.maxDelta {
+			dt = l.maxDelta
+		}
+		if dt > 0 {
+			steps = 1
+		}
+		l.alpha = 1
+	}
+	alpha := l.alpha
+	l.mu.Unlock()
+
+	for i := 0; i < steps; i++ {
+		if err := l.world.Tick(dt); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		l.ticks++
+		l.mu.Unlock()
+	}
+	l.world.Render(alpha)
+	return nil
+}
+
+// Pause 暂停执行Tick, 暂停期间经过的时间被丢掉, 仍然每帧调用Render.
+func (l *Loop) Pause() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.paused = true
+}
+
+// Resume 从暂停中恢复, 也会丢掉还没执行的Step.
+func (l *Loop) Resume() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.paused = false
+	l.pending = 0
+}
+
+func (l *Loop) Paused() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.paused
+}
+
+// Step 在暂停时让下一帧执行一次Tick, dt是固定步长, 没有固定步长时是DefaultStep.
+// 没有暂停时不起作用.
+func (l *Loop) Step() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.paused {
+		l.pending++
+	}
+}
+
+// SetTimeScale 设置时间缩放, 2表示两倍速. 0时不再累计时间, 不执行Tick但是照常Render. 负数按0处理.
+func (l *Loop) SetTimeScale(scale float64) {
+	if scale < 0 {
+		scale = 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scale = scale
+}
+
+func (l *Loop) TimeScale() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.scale
+}
+
+// Alpha 返回上一帧传给Render的alpha.
+func (l *Loop) Alpha() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.alpha
+}
+
+// Ticks 返回一共执行了多少次Tick.
+func (l *Loop) Ticks() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ticks
+}
+
+func (l *Loop) stepLength() time.Duration {
+	if l.step > 0 {
+		return l.step
+	}
+	return DefaultStep
+}
+
+func (l *Loop) scaled(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * l.scale)
+}
@@ -0,0 +1,212 @@
+package entitas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type loopSystem struct {
+	dts    []time.Duration
+	alphas []float64
+	onInit func()
+	onTick func()
+}
+
+func (s *loopSystem) Initialize() {
+	if s.onInit != nil {
+		s.onInit()
+	}
+}
+
+func (s *loopSystem) Execute(dt time.Duration) {
+	s.dts = append(s.dts, dt)
+	if s.onTick != nil {
+		s.onTick()
+	}
+}
+
+func (s *loopSystem) Render(alpha float64) { s.alphas = append(s.alphas, alpha) }
+
+func TestLoop(t *testing.T) {
+	Convey("Given a loop with a fixed step", t, func() {
+		clock := NewFakeClock(time.Unix(0, 0))
+		s := &loopSystem{}
+		w := NewWorld(NewContext(0)).AddSystem(s)
+		l := NewLoop(w, WithClock(clock), WithFixedStep(10*time.Millisecond), WithMaxSteps(4))
+		l.Advance()
+
+		Convey("The first frame initializes the world and renders", func() {
+			So(w.initialized, ShouldBeTrue)
+			So(s.dts, ShouldBeEmpty)
+			So(s.alphas, ShouldResemble, []float64{0})
+		})
+
+		Convey("It runs a tick for every full step", func() {
+			clock.Advance(35 * time.Millisecond)
+			l.Advance()
+			So(s.dts, ShouldResemble, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond})
+			So(l.Alpha(), ShouldAlmostEqual, 0.5)
+
+			clock.Advance(5 * time.Millisecond)
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 4)
+			So(l.Alpha(), ShouldAlmostEqual, 0)
+			So(s.alphas, ShouldHaveLength, 3)
+		})
+
+		Convey("It drops time it can't catch up with", func() {
+			clock.Advance(time.Second + 5*time.Millisecond)
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 4)
+			So(l.Alpha(), ShouldAlmostEqual, 0.5)
+
+			clock.Advance(5 * time.Millisecond)
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 5)
+		})
+
+		Convey("It scales time", func() {
+			l.SetTimeScale(2)
+			clock.Advance(10 * time.Millisecond)
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 2)
+
+			l.SetTimeScale(0.5)
+			clock.Advance(10 * time.Millisecond)
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 2)
+			So(l.Alpha(), ShouldAlmostEqual, 0.5)
+
+			l.SetTimeScale(-1)
+			So(l.TimeScale(), ShouldEqual, 0)
+		})
+
+		Convey("When paused", func() {
+			l.Pause()
+			clock.Advance(100 * time.Millisecond)
+			l.Advance()
+
+			Convey("It doesn't tick but still renders", func() {
+				So(l.Paused(), ShouldBeTrue)
+				So(s.dts, ShouldBeEmpty)
+				So(s.alphas, ShouldHaveLength, 2)
+			})
+
+			Convey("It runs single steps", func() {
+				l.Step()
+				l.Step()
+				l.Advance()
+				So(s.dts, ShouldResemble, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond})
+				l.Advance()
+				So(l.Ticks(), ShouldEqual, 2)
+			})
+
+			Convey("It discards the paused time on resume", func() {
+				l.Resume()
+				clock.Advance(10 * time.Millisecond)
+				l.Advance()
+				So(l.Ticks(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("Step does nothing while running", func() {
+			l.Step()
+			l.Advance()
+			So(l.Ticks(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Systems can control the loop from Initialize", t, func() {
+		clock := NewFakeClock(time.Unix(0, 0))
+		s := &loopSystem{}
+		l := NewLoop(NewWorld(NewContext(0)).AddSystem(s), WithClock(clock), WithFixedStep(10*time.Millisecond))
+		s.onInit = func() {
+			l.Pause()
+			l.SetTimeScale(2)
+		}
+		done := make(chan struct{})
+		go func() {
+			l.Advance()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Advance deadlocked in Initialize")
+		}
+		So(l.Paused(), ShouldBeTrue)
+		So(l.TimeScale(), ShouldEqual, 2)
+		clock.Advance(100 * time.Millisecond)
+		l.Advance()
+		So(s.dts, ShouldBeEmpty)
+	})
+
+	Convey("Given a loop with a variable step", t, func() {
+		clock := NewFakeClock(time.Unix(0, 0))
+		s := &loopSystem{}
+		w := NewWorld(NewContext(0)).AddSystem(s)
+		l := NewLoop(w, WithClock(clock))
+		l.Advance()
+
+		Convey("It ticks once per frame with the elapsed time", func() {
+			clock.Advance(7 * time.Millisecond)
+			l.Advance()
+			l.SetTimeScale(3)
+			clock.Advance(5 * time.Millisecond)
+			l.Advance()
+			So(s.dts, ShouldResemble, []time.Duration{7 * time.Millisecond, 15 * time.Millisecond})
+			So(l.Alpha(), ShouldEqual, 1)
+		})
+
+		Convey("It clamps the elapsed time to the max delta", func() {
+			s := &loopSystem{}
+			l := NewLoop(NewWorld(NewContext(0)).AddSystem(s), WithClock(clock), WithMaxDelta(50*time.Millisecond))
+			l.Advance()
+			clock.Advance(time.Second)
+			l.Advance()
+			clock.Advance(20 * time.Millisecond)
+			l.Advance()
+			So(s.dts, ShouldResemble, []time.Duration{50 * time.Millisecond, 20 * time.Millisecond})
+		})
+
+		Convey("It steps with the default step while paused", func() {
+			l.Pause()
+			l.Step()
+			clock.Advance(time.Second)
+			l.Advance()
+			So(s.dts, ShouldResemble, []time.Duration{DefaultStep})
+		})
+	})
+
+	Convey("Given a loop running on a fake clock", t, func() {
+		clock := NewFakeClock(time.Unix(0, 0))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := &loopSystem{}
+		w := NewWorld(NewContext(0)).AddSystem(s)
+		l := NewLoop(w, WithClock(clock), WithFixedStep(20*time.Millisecond), WithFrameInterval(10*time.Millisecond))
+		s.onTick = func() {
+			if l.Ticks() == 9 {
+				cancel()
+			}
+		}
+
+		Convey("It runs until the context is cancelled", func() {
+			err := l.Run(ctx)
+			So(err, ShouldEqual, context.Canceled)
+			So(l.Ticks(), ShouldEqual, 10)
+			So(len(s.alphas), ShouldEqual, 21)
+			So(clock.Now().Sub(time.Unix(0, 0)), ShouldEqual, 200*time.Millisecond)
+		})
+	})
+
+	Convey("The system clock stops sleeping when the context is done", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		So(SystemClock.Sleep(ctx, time.Hour), ShouldEqual, context.Canceled)
+		So(SystemClock.Sleep(context.Background(), time.Millisecond), ShouldBeNil)
+	})
+}
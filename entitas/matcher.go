@@ -2,20 +2,19 @@ package entitas
 
 import (
 	"fmt"
+	"hash/fnv"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
-const (
-	componentHashFactor uint16 = 647
-	allHashFactor              = 653
-	anyHashFactor              = 659
-	noneHashFactor             = 661
-)
+const componentHashFactor uint16 = 647
 
 type Matcher interface {
 	Matches(entity Entity) bool      // 判断entity是否应该被matcher匹配
-	Hash() MatcherHash               // 获取match-id. 如果发生了hash碰撞，会有bug, 所以实际上是ID而不是hash.
+	Hash() MatcherHash               // 获取hash. 只用于调试和比较, Context用key区分matcher, 见KeyedMatcher.
 	ComponentTypes() []ComponentType // 获取这个matcher所关心的组件列表.
 	Equals(m Matcher) bool           // 必将两个matcher是不是同一种.
 	String() string                  // 调试用.
@@ -23,18 +22,60 @@ type Matcher interface {
 
 type MatcherHash uint
 
+// keyedMatcher 是内置的matcher, key是由结构决定的规范化字符串, 结构相同的matcher的key一定相同, 不会碰撞.
+type keyedMatcher interface {
+	key() string
+}
+
+// KeyedMatcher 由自定义的matcher实现, MatcherKey返回描述匹配条件的规范化字符串.
+// 同一个Context里类型和key都相同的matcher共用一个group, 所以条件不同的matcher的key必须不同.
+// 没有实现KeyedMatcher的自定义matcher按身份区分: 指针只和同一个指针共用group, 值类型和相等的值共用.
+type KeyedMatcher interface {
+	Matcher
+	MatcherKey() string
+}
+
+// matcherKey 返回m的唯一标识, Context用它缓存group.
+func matcherKey(m Matcher) string {
+	switch k := m.(type) {
+	case keyedMatcher:
+		return k.key()
+	case KeyedMatcher:
+		return fmt.Sprintf("%T:%s", m, k.MatcherKey())
+	}
+	switch v := reflect.ValueOf(m); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.Slice, reflect.UnsafePointer:
+		return fmt.Sprintf("%T@%#x", m, v.Pointer())
+	default:
+		return fmt.Sprintf("%T=%#v", m, m)
+	}
+}
+
+func hashKey(key string) MatcherHash {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return MatcherHash(h.Sum64())
+}
+
 // --- BaseMatcher ------------------------------------------------------------
 
 type BaseMatcher struct {
-	matchers map[MatcherHash]Matcher
+	matchers map[string]Matcher
+	keys     string
 	hash     MatcherHash
 }
 
 func newBaseMatcher(ms ...Matcher) BaseMatcher {
-	b := BaseMatcher{matchers: make(map[MatcherHash]Matcher)}
+	b := BaseMatcher{matchers: make(map[string]Matcher)}
 	for _, m := range ms {
-		b.matchers[m.Hash()] = m
+		b.matchers[matcherKey(m)] = m
+	}
+	keys := make([]string, 0, len(b.matchers))
+	for k := range b.matchers {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	b.keys = strings.Join(keys, ",")
 	return b
 }
 
@@ -61,9 +102,9 @@ func (b *BaseMatcher) ComponentTypes() []ComponentType {
 type AllMatcher struct{ BaseMatcher }
 
 func AllOf(ms ...Matcher) Matcher {
-	b := newBaseMatcher(ms...)
-	b.hash = Hash(allHashFactor, ms...)
-	return &AllMatcher{b}
+	a := &AllMatcher{newBaseMatcher(ms...)}
+	a.hash = hashKey(a.key())
+	return a
 }
 
 func (a *AllMatcher) Matches(e Entity) bool {
@@ -76,21 +117,11 @@ func (a *AllMatcher) Matches(e Entity) bool {
 }
 
 func (a *AllMatcher) Equals(m Matcher) bool {
-	switch m.(type) {
-	case *AllMatcher:
-		for h, m := range m.(*AllMatcher).matchers {
-			if am, ok := a.matchers[h]; ok {
-				if !m.Equals(am) {
-					return false
-				}
-			} else {
-				return false
-			}
-		}
-		return true
-	default:
-		return false
-	}
+	return matcherKey(m) == a.key()
+}
+
+func (a *AllMatcher) key() string {
+	return "AllOf(" + a.keys + ")"
 }
 
 func (a *AllMatcher) String() string {
@@ -102,9 +133,9 @@ func (a *AllMatcher) String() string {
 type AnyMatcher struct{ BaseMatcher }
 
 func AnyOf(ms ...Matcher) Matcher {
-	b := newBaseMatcher(ms...)
-	b.hash = Hash(anyHashFactor, ms...)
-	return &AnyMatcher{b}
+	a := &AnyMatcher{newBaseMatcher(ms...)}
+	a.hash = hashKey(a.key())
+	return a
 }
 
 func (a *AnyMatcher) Matches(e Entity) bool {
@@ -117,7 +148,11 @@ func (a *AnyMatcher) Matches(e Entity) bool {
 }
 
 func (a *AnyMatcher) Equals(m Matcher) bool {
-	return false // TODO: Implement
+	return matcherKey(m) == a.key()
+}
+
+func (a *AnyMatcher) key() string {
+	return "AnyOf(" + a.keys + ")"
 }
 
 func (a *AnyMatcher) String() string {
@@ -129,9 +164,9 @@ func (a *AnyMatcher) String() string {
 type NoneMatcher struct{ BaseMatcher }
 
 func NoneOf(ms ...Matcher) Matcher {
-	b := newBaseMatcher(ms...)
-	b.hash = Hash(noneHashFactor, ms...)
-	return &NoneMatcher{b}
+	n := &NoneMatcher{newBaseMatcher(ms...)}
+	n.hash = hashKey(n.key())
+	return n
 }
 
 func (n *NoneMatcher) Matches(e Entity) bool {
@@ -144,15 +179,196 @@ func (n *NoneMatcher) Matches(e Entity) bool {
 }
 
 func (n *NoneMatcher) Equals(m Matcher) bool {
-	return false // TODO: Implement
+	return matcherKey(m) == n.key()
+}
+
+func (n *NoneMatcher) key() string {
+	return "NoneOf(" + n.keys + ")"
 }
 
 func (n *NoneMatcher) String() string {
 	return fmt.Sprintf("NoneOf(%v)", print(n.matchers))
 }
 
+// --- Compound -------------------------------------------------------------
+
+// CompoundMatcher 同时包含AllOf, AnyOf和NoneOf三组组件, 用NewMatcher创建:
+//
+//	m := NewMatcher().AllOf(Position, Velocity).NoneOf(Frozen)
+//
+// 三组组件都是排好序并且去重的, 所以添加的顺序和重复不影响Equals和Context.Group.
+// 每次调用AllOf, AnyOf, NoneOf都返回一个新的matcher, 原来的不会被修改.
+type CompoundMatcher struct {
+	all, any, none []ComponentType
+	hash           MatcherHash
+}
+
+// NewMatcher 返回一个匹配所有entity的CompoundMatcher.
+func NewMatcher() *CompoundMatcher {
+	m := &CompoundMatcher{}
+	m.hash = hashKey(m.key())
+	return m
+}
+
+// AllOf 要求entity有ts里所有的组件.
+func (m *CompoundMatcher) AllOf(ts ...ComponentType) *CompoundMatcher {
+	c := *m
+	c.all = mergeTypes(m.all, ts)
+	c.hash = hashKey(c.key())
+	return &c
+}
+
+// AnyOf 要求entity至少有ts里的一个组件. 多次调用时合并成同一组.
+func (m *CompoundMatcher) AnyOf(ts ...ComponentType) *CompoundMatcher {
+	c := *m
+	c.any = mergeTypes(m.any, ts)
+	c.hash = hashKey(c.key())
+	return &c
+}
+
+// NoneOf 要求entity没有ts里的任何一个组件.
+func (m *CompoundMatcher) NoneOf(ts ...ComponentType) *CompoundMatcher {
+	c := *m
+	c.none = mergeTypes(m.none, ts)
+	c.hash = hashKey(c.key())
+	return &c
+}
+
+func (m *CompoundMatcher) Matches(e Entity) bool {
+	if !e.HasComponent(m.all...) {
+		return false
+	}
+	if len(m.any) > 0 && !e.HasAnyComponent(m.any...) {
+		return false
+	}
+	return len(m.none) == 0 || !e.HasAnyComponent(m.none...)
+}
+
+func (m *CompoundMatcher) Hash() MatcherHash {
+	return m.hash
+}
+
+// ComponentTypes 返回三组组件的并集, 按类型排序.
+func (m *CompoundMatcher) ComponentTypes() []ComponentType {
+	return mergeTypes(mergeTypes(m.all, m.any), m.none)
+}
+
+func (m *CompoundMatcher) Equals(o Matcher) bool {
+	return matcherKey(o) == m.key()
+}
+
+func (m *CompoundMatcher) key() string {
+	return "Matcher(" + typesKey(m.all) + ";" + typesKey(m.any) + ";" + typesKey(m.none) + ")"
+}
+
+func (m *CompoundMatcher) String() string {
+	var b strings.Builder
+	b.WriteString("NewMatcher()")
+	for _, part := range []struct {
+		name  string
+		types []ComponentType
+	}{{"AllOf", m.all}, {"AnyOf", m.any}, {"NoneOf", m.none}} {
+		if len(part.types) == 0 {
+			continue
+		}
+		names := make([]string, len(part.types))
+		for i, t := range part.types {
+			names[i] = t.String()
+		}
+		fmt.Fprintf(&b, ".%s(%s)", part.name, strings.Join(names, ", "))
+	}
+	return b.String()
+}
+
+// mergeTypes 返回a和b的并集, 排好序并且去重. 不会修改a和b.
+func mergeTypes(a, b []ComponentType) []ComponentType {
+	types := make([]ComponentType, 0, len(a)+len(b))
+	types = append(append(types, a...), b...)
+	sort.Sort(TypesByType(types))
+	n := 0
+	for i, t := range types {
+		if i == 0 || t != types[n-1] {
+			types[n] = t
+			n++
+		}
+	}
+	return types[:n]
+}
+
+func typesKey(ts []ComponentType) string {
+	keys := make([]string, len(ts))
+	for i, t := range ts {
+		keys[i] = t.key()
+	}
+	return strings.Join(keys, ",")
+}
+
+// --- Where ----------------------------------------------------------------
+
+var lastPredicate atomic.Uint64
+
+// ValueMatcher 匹配有某个组件并且组件的值满足条件的entity, 用Where或者WhereNamed创建:
+//
+//	dead := Where(ComType_health, func(c Component) bool { return c.(*Health).HP <= 0 })
+//
+// 组件被添加, 替换或删除时group会重新判断, 直接修改组件内部的值不会, 需要用ReplaceComponent.
+// pred不能修改entity, 也不能访问Context.
+type ValueMatcher struct {
+	t    ComponentType
+	pred func(Component) bool
+	id   string // 区分不同的条件
+	hash MatcherHash
+}
+
+// Where 返回一个ValueMatcher. 每次调用得到的matcher都互不相等, 即使pred是同一个函数,
+// 所以要共用同一个group时应该保存返回值, 或者用WhereNamed.
+func Where(t ComponentType, pred func(Component) bool) *ValueMatcher {
+	return newValueMatcher(t, "#"+strconv.FormatUint(lastPredicate.Add(1), 10), pred)
+}
+
+// WhereNamed 和Where一样, 但是用name区分条件: 组件类型和name都相同的matcher被认为相等,
+// 调用者要保证它们的pred也一样.
+func WhereNamed(t ComponentType, name string, pred func(Component) bool) *ValueMatcher {
+	return newValueMatcher(t, strconv.Quote(name), pred)
+}
+
+func newValueMatcher(t ComponentType, id string, pred func(Component) bool) *ValueMatcher {
+	m := &ValueMatcher{t: t, pred: pred, id: id}
+	m.hash = hashKey(m.key())
+	return m
+}
+
+func (m *ValueMatcher) Matches(e Entity) bool {
+	if !e.HasComponent(m.t) {
+		return false
+	}
+	c, err := e.Component(m.t)
+	return err == nil && m.pred(c)
+}
+
+func (m *ValueMatcher) Hash() MatcherHash {
+	return m.hash
+}
+
+func (m *ValueMatcher) ComponentTypes() []ComponentType {
+	return []ComponentType{m.t}
+}
+
+func (m *ValueMatcher) Equals(o Matcher) bool {
+	return matcherKey(o) == m.key()
+}
+
+func (m *ValueMatcher) key() string {
+	return "Where(" + m.t.key() + "," + m.id + ")"
+}
+
+func (m *ValueMatcher) String() string {
+	return fmt.Sprintf("Where(%v, %s)", m.t, m.id)
+}
+
 // --- Utilities --------------------------------------------------------------
 
+// Hash 把一组matcher的Hash合成一个. 不同的组合可能得到相同的结果, 只能用来做快速的比较.
 func Hash(factor uint, ms ...Matcher) MatcherHash {
 	var hash uint
 	for _, m := range ms {
@@ -162,7 +378,7 @@ func Hash(factor uint, ms ...Matcher) MatcherHash {
 	return MatcherHash(hash)
 }
 
-func print(matchers map[MatcherHash]Matcher) string {
+func print(matchers map[string]Matcher) string {
 	ms := make([]string, 0)
 	for _, m := range matchers {
 		ms = append(ms, m.String())
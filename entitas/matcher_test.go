@@ -2,6 +2,7 @@ package entitas
 
 import (
 	"sort"
+	"strconv"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -74,7 +75,7 @@ func TestMatcher(t *testing.T) {
 			Convey("It equals equal AllOfMatcher", func() {
 				m1 := allOfAB()
 				m2 := allOfAB()
-				So(m1, ShouldNotEqual, m2)
+				So(m1, ShouldNotPointTo, m2)
 				So(m1.Equals(m2), ShouldBeTrue)
 			})
 
@@ -82,7 +83,7 @@ func TestMatcher(t *testing.T) {
 				m1 := allOfAB()
 				m2 := AllOf(ComponentB, ComponentA)
 
-				So(m1, ShouldNotEqual, m2)
+				So(m1, ShouldNotPointTo, m2)
 				So(m1.Equals(m2), ShouldBeTrue)
 			})
 
@@ -110,7 +111,31 @@ func TestMatcher(t *testing.T) {
 				So(m1.Equals(m2), ShouldBeFalse)
 			})
 
-			// TODO: Add test of AnyOf and NoneOf equals/hash
+			Convey("AnyOf and NoneOf equal when their components are the same", func() {
+				So(AnyOf(ComponentA, ComponentB).Equals(AnyOf(ComponentB, ComponentA)), ShouldBeTrue)
+				So(NoneOf(ComponentA, ComponentB).Equals(NoneOf(ComponentB, ComponentA, ComponentA)), ShouldBeTrue)
+				So(AnyOf(ComponentA, ComponentB).Hash(), ShouldEqual, AnyOf(ComponentB, ComponentA).Hash())
+				So(NoneOf(ComponentA).Hash(), ShouldEqual, NoneOf(ComponentA).Hash())
+			})
+
+			Convey("AnyOf and NoneOf don't equal different matchers", func() {
+				So(AnyOf(ComponentA, ComponentB).Equals(AnyOf(ComponentA)), ShouldBeFalse)
+				So(AnyOf(ComponentA, ComponentB).Equals(NoneOf(ComponentA, ComponentB)), ShouldBeFalse)
+				So(NoneOf(ComponentA).Equals(AllOf(ComponentA)), ShouldBeFalse)
+				So(NoneOf(ComponentA).Equals(ComponentA), ShouldBeFalse)
+			})
+
+			Convey("A component type only equals itself", func() {
+				So(ComponentA.Equals(ComponentA), ShouldBeTrue)
+				So(ComponentA.Equals(ComponentB), ShouldBeFalse)
+				So(ComponentA.Equals(AllOf(ComponentA)), ShouldBeFalse)
+				So(ComponentA.Equals(NoneOf(ComponentA)), ShouldBeFalse)
+			})
+
+			Convey("Matchers whose XOR hashes collide don't equal", func() {
+				So(AllOf(ComponentA, ComponentB).Equals(AnyOf(ComponentC)), ShouldBeFalse)
+				So(AnyOf(ComponentC).Equals(AllOf(ComponentA, ComponentB)), ShouldBeFalse)
+			})
 
 			a := ComponentType(0)
 			b := ComponentType(1)
@@ -386,6 +411,155 @@ func TestMatcher(t *testing.T) {
 	})
 }
 
+func TestCompoundMatcher(t *testing.T) {
+	Convey("Subject: Compound matcher", t, func() {
+		eA := NewEntity(1)
+		eA.AddComponent(NewComponentA(1))
+		eAB := NewEntity(2)
+		eAB.AddComponent(NewComponentA(2))
+		eAB.AddComponent(NewComponentB(2.2))
+		eAC := NewEntity(3)
+		eAC.AddComponent(NewComponentA(3))
+		eAC.AddComponent(NewComponentC())
+		eABD := NewEntity(4)
+		eABD.AddComponent(NewComponentA(4))
+		eABD.AddComponent(NewComponentB(4.4))
+		eABD.AddComponent(NewComponentD())
+
+		Convey("An empty matcher matches everything", func() {
+			m := NewMatcher()
+			So(m.Matches(eA), ShouldBeTrue)
+			So(m.Matches(NewEntity(-1)), ShouldBeTrue)
+			So(m.ComponentTypes(), ShouldBeEmpty)
+			So(m.String(), ShouldEqual, "NewMatcher()")
+		})
+
+		Convey("It combines AllOf, AnyOf and NoneOf", func() {
+			m := NewMatcher().AllOf(ComponentA).AnyOf(ComponentB, ComponentC).NoneOf(ComponentD)
+			So(m.Matches(eA), ShouldBeFalse)
+			So(m.Matches(eAB), ShouldBeTrue)
+			So(m.Matches(eAC), ShouldBeTrue)
+			So(m.Matches(eABD), ShouldBeFalse)
+			So(m.ComponentTypes(), ShouldResemble, []ComponentType{ComponentA, ComponentB, ComponentC, ComponentD})
+		})
+
+		Convey("It normalizes component sets", func() {
+			m1 := NewMatcher().AllOf(ComponentB, ComponentA, ComponentB).NoneOf(ComponentD)
+			m2 := NewMatcher().NoneOf(ComponentD, ComponentD).AllOf(ComponentA).AllOf(ComponentB)
+			So(m1, ShouldNotPointTo, m2)
+			So(m1.Equals(m2), ShouldBeTrue)
+			So(m1.Hash(), ShouldEqual, m2.Hash())
+			So(m1.String(), ShouldEqual, "NewMatcher().AllOf(0, 1).NoneOf(3)")
+			So(m2.String(), ShouldEqual, m1.String())
+		})
+
+		Convey("It doesn't equal a matcher with the same types in another set", func() {
+			all := NewMatcher().AllOf(ComponentA, ComponentB)
+			So(all.Equals(NewMatcher().AnyOf(ComponentA, ComponentB)), ShouldBeFalse)
+			So(all.Equals(NewMatcher().AllOf(ComponentA).NoneOf(ComponentB)), ShouldBeFalse)
+			So(all.Equals(AllOf(ComponentA, ComponentB)), ShouldBeFalse)
+			So(NewMatcher().Equals(NewMatcher()), ShouldBeTrue)
+		})
+
+		Convey("It doesn't modify the matcher it was built from", func() {
+			base := NewMatcher().AllOf(ComponentA)
+			withB := base.AllOf(ComponentB)
+			withC := base.AllOf(ComponentC)
+			So(base.String(), ShouldEqual, "NewMatcher().AllOf(0)")
+			So(withB.String(), ShouldEqual, "NewMatcher().AllOf(0, 1)")
+			So(withC.String(), ShouldEqual, "NewMatcher().AllOf(0, 2)")
+		})
+
+		Convey("It can be nested in other matchers", func() {
+			m := AnyOf(NewMatcher().AllOf(ComponentA).NoneOf(ComponentB), ComponentD)
+			So(m.Matches(eA), ShouldBeTrue)
+			So(m.Matches(eAB), ShouldBeFalse)
+			So(m.Matches(eABD), ShouldBeTrue)
+			So(m.Equals(AnyOf(ComponentD, NewMatcher().NoneOf(ComponentB).AllOf(ComponentA))), ShouldBeTrue)
+		})
+	})
+}
+
+func TestValueMatcher(t *testing.T) {
+	Convey("Subject: Value matchers", t, func() {
+		positive := func(c Component) bool { return c.(*componentA).value > 0 }
+
+		Convey("It matches on the component value", func() {
+			m := Where(ComponentA, positive)
+			e := NewEntity(1)
+			So(m.Matches(e), ShouldBeFalse)
+			e.AddComponent(NewComponentA(1))
+			So(m.Matches(e), ShouldBeTrue)
+			e.ReplaceComponent(NewComponentA(-1))
+			So(m.Matches(e), ShouldBeFalse)
+			So(m.ComponentTypes(), ShouldResemble, []ComponentType{ComponentA})
+		})
+
+		Convey("Each Where is a different matcher", func() {
+			m1 := Where(ComponentA, positive)
+			m2 := Where(ComponentA, positive)
+			So(m1.Equals(m1), ShouldBeTrue)
+			So(m1.Equals(m2), ShouldBeFalse)
+			So(m1.String(), ShouldNotEqual, m2.String())
+			So(AllOf(m1, ComponentB).Equals(AllOf(ComponentB, m1)), ShouldBeTrue)
+			So(AllOf(m1, ComponentB).Equals(AllOf(m2, ComponentB)), ShouldBeFalse)
+		})
+
+		Convey("Named predicates equal by type and name", func() {
+			m := WhereNamed(ComponentA, "positive", positive)
+			So(m.Equals(WhereNamed(ComponentA, "positive", positive)), ShouldBeTrue)
+			So(m.Hash(), ShouldEqual, WhereNamed(ComponentA, "positive", positive).Hash())
+			So(m.Equals(WhereNamed(ComponentB, "positive", positive)), ShouldBeFalse)
+			So(m.Equals(WhereNamed(ComponentA, "negative", positive)), ShouldBeFalse)
+			So(m.Equals(ComponentA), ShouldBeFalse)
+			So(m.String(), ShouldEqual, `Where(0, "positive")`)
+		})
+	})
+}
+
 func allOfAB() Matcher {
 	return AllOf(ComponentA, ComponentB)
 }
+
+// hashedMatcher 是没有实现KeyedMatcher的自定义matcher, Hash故意都相同.
+type hashedMatcher struct {
+	t ComponentType
+}
+
+func (m *hashedMatcher) Matches(e Entity) bool           { return e.HasComponent(m.t) }
+func (m *hashedMatcher) Hash() MatcherHash               { return 123 }
+func (m *hashedMatcher) ComponentTypes() []ComponentType { return []ComponentType{m.t} }
+func (m *hashedMatcher) Equals(o Matcher) bool           { return m == o }
+func (m *hashedMatcher) String() string                  { return "hashed" }
+
+type keyedTestMatcher struct {
+	hashedMatcher
+}
+
+func (m *keyedTestMatcher) MatcherKey() string { return strconv.Itoa(int(m.t)) }
+
+func TestCustomMatcher(t *testing.T) {
+	Convey("Given custom matchers with colliding hashes", t, func() {
+		p := NewContext(0)
+		p.CreateEntity(NewComponentA(1))
+		a, b := &hashedMatcher{t: ComponentA}, &hashedMatcher{t: ComponentB}
+
+		Convey("They get separate groups", func() {
+			ga, gb := p.Group(a), p.Group(b)
+			So(ga, ShouldNotPointTo, gb)
+			So(len(ga.Entities()), ShouldEqual, 1)
+			So(len(gb.Entities()), ShouldEqual, 0)
+		})
+
+		Convey("The same matcher gets the same group", func() {
+			So(p.Group(a), ShouldPointTo, p.Group(a))
+			So(p.Group(a), ShouldNotPointTo, p.Group(&hashedMatcher{t: ComponentA}))
+		})
+
+		Convey("Matchers with the same key share a group", func() {
+			k := p.Group(&keyedTestMatcher{hashedMatcher{t: ComponentA}})
+			So(p.Group(&keyedTestMatcher{hashedMatcher{t: ComponentA}}), ShouldPointTo, k)
+			So(p.Group(&keyedTestMatcher{hashedMatcher{t: ComponentB}}), ShouldNotPointTo, k)
+		})
+	})
+}
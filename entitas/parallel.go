@@ -0,0 +1,77 @@
+package entitas
+
+import "sync"
+
+// ParallelForEachDeferred 和Group.ParallelForEach一样分批并行处理entity,
+// 每一批有自己的命令缓冲, 全部处理完之后按批次顺序追加到cmd里,
+// 所以只要每批内部的处理是确定的, cmd里命令的顺序就是确定的.
+func ParallelForEachDeferred(g Group, workers int, cmd *EntityCommandBuffer, fn func(e Entity, cmd *EntityCommandBuffer)) {
+	entities := g.Entities()
+	n := batchCount(len(entities), workers)
+	buffers := make([]*EntityCommandBuffer, n)
+	forEachBatch(entities, n, func(batch int, entities []Entity) {
+		b := NewEntityCommandBuffer()
+		for _, e := range entities {
+			fn(e, b)
+		}
+		buffers[batch] = b
+	})
+	for _, b := range buffers {
+		if b != nil {
+			cmd.Append(b)
+		}
+	}
+}
+
+func batchCount(n, workers int) int {
+	if workers < 1 {
+		workers = 1
+	}
+	if n < workers {
+		return n
+	}
+	return workers
+}
+
+// forEachBatch 把entities平均分成n个连续的批次, 每批在一个goroutine里处理.
+// fn里的panic会在所有批次结束后在调用者的goroutine里重新抛出.
+func forEachBatch(entities []Entity, n int, fn func(batch int, entities []Entity)) {
+	if n <= 1 {
+		if len(entities) > 0 {
+			fn(0, entities)
+		}
+		return
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failure interface{}
+	)
+	size := (len(entities) + n - 1) / n
+	for batch := 0; batch*size < len(entities); batch++ {
+		start, end := batch*size, (batch+1)*size
+		if end > len(entities) {
+			end = len(entities)
+		}
+		wg.Add(1)
+		go func(batch int, entities []Entity) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					mu.Lock()
+					if failure == nil {
+						failure = r
+					}
+					mu.Unlock()
+				}
+			}()
+			fn(batch, entities)
+		}(batch, entities[start:end])
+	}
+	wg.Wait()
+
+	if failure != nil {
+		panic(failure)
+	}
+}
@@ -0,0 +1,97 @@
+package entitas
+
+import (
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParallelForEach(t *testing.T) {
+	Convey("Given a group with many entities", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		for i := 0; i < 1000; i++ {
+			p.CreateEntity(NewComponentA(i))
+		}
+
+		Convey("It visits every entity exactly once", func() {
+			for _, workers := range []int{0, 1, 3, 8, 5000} {
+				var visits int64
+				var sum int64
+				g.ParallelForEach(workers, func(e Entity) {
+					atomic.AddInt64(&visits, 1)
+					atomic.AddInt64(&sum, int64(e.GetComponent(ComponentA).(*componentA).value))
+				})
+				So(visits, ShouldEqual, 1000)
+				So(sum, ShouldEqual, 999*1000/2)
+			}
+		})
+
+		Convey("It mutates components in place", func() {
+			g.ParallelForEach(4, func(e Entity) {
+				e.GetComponent(ComponentA).(*componentA).value++
+			})
+			sum := 0
+			for _, e := range g.Entities() {
+				sum += e.GetComponent(ComponentA).(*componentA).value
+			}
+			So(sum, ShouldEqual, 999*1000/2+1000)
+		})
+
+		Convey("It partitions entities into contiguous chunks", func() {
+			var chunks int64
+			var visits int64
+			g.ParallelForEachChunk(4, func(entities []Entity) {
+				atomic.AddInt64(&chunks, 1)
+				atomic.AddInt64(&visits, int64(len(entities)))
+			})
+			So(chunks, ShouldEqual, 4)
+			So(visits, ShouldEqual, 1000)
+		})
+
+		Convey("It propagates panics", func() {
+			So(func() {
+				g.ParallelForEach(4, func(e Entity) { panic("boom") })
+			}, ShouldPanicWith, "boom")
+		})
+
+		Convey("It defers structural changes in deterministic order", func() {
+			cmd := NewEntityCommandBuffer()
+			ParallelForEachDeferred(g, 4, cmd, func(e Entity, cmd *EntityCommandBuffer) {
+				if e.GetComponent(ComponentA).(*componentA).value%2 == 0 {
+					cmd.DestroyEntity(e)
+				}
+			})
+			So(len(g.Entities()), ShouldEqual, 1000)
+			So(cmd.Len(), ShouldEqual, 500)
+
+			entities := g.Entities()
+			var expected []Entity
+			for _, e := range entities {
+				if e.GetComponent(ComponentA).(*componentA).value%2 == 0 {
+					expected = append(expected, e)
+				}
+			}
+			for i, c := range cmd.commands {
+				So(c.entity, ShouldEqual, expected[i])
+			}
+
+			So(cmd.Playback(p), ShouldBeNil)
+			So(len(g.Entities()), ShouldEqual, 500)
+		})
+
+		Convey("It keeps pending entities usable after appending", func() {
+			cmd := NewEntityCommandBuffer()
+			var pending *PendingEntity
+			ParallelForEachDeferred(g, 1, cmd, func(e Entity, b *EntityCommandBuffer) {
+				if pending == nil {
+					pending = b.CreateEntity(NewComponentB(1))
+				}
+			})
+			pending.AddComponent(NewComponentC())
+			So(cmd.Playback(p), ShouldBeNil)
+			So(pending.Entity().HasComponent(ComponentB, ComponentC), ShouldBeTrue)
+		})
+	})
+}
@@ -1,66 +1,283 @@
 package entitas
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+var ErrUniqueComponentExists = errors.New("unique component exists")
 
 type Context interface {
-	CreateEntity(cs ...Component) Entity // 创建entity
-	Entities() []Entity                  // 获取pool创建的所有还在的entity
-	Count() int                          // entity数量
-	HasEntity(e Entity) bool             // 是否包含某个entity
-	DestroyEntity(e Entity)              // 删除entity
-	DestroyAllEntities()                 // -
-	Group(m Matcher) Group               // 获取包含满足条件的所有entities的group. group其实就是一个增强版的entities list.
+	CreateEntity(cs ...Component) Entity                     // 创建entity. 组件被group的检查函数拒绝时panic, 这时entity还没有被创建
+	Instantiate(b *Blueprint, overrides ...Component) Entity // 用Blueprint创建entity, 组件是默认值的深拷贝. panic的情况和CreateEntity一样
+	Entities() []Entity                                      // 获取pool创建的所有还在的entity, 按ID排序
+	Count() int                                              // entity数量
+	HasEntity(e Entity) bool                                 // 是否包含某个entity
+	DestroyEntity(e Entity)                                  // 删除entity
+	DestroyAllEntities()                                     // -
+	Group(m Matcher) Group                                   // 获取包含满足条件的所有entities的group. group其实就是一个增强版的entities list.
+	Handle(e Entity) EntityHandle                            // 获取entity的handle, entity不属于当前pool时返回InvalidEntityHandle
+	Resolve(h EntityHandle) (Entity, bool)                   // 通过handle找到entity, entity已经被销毁时返回false
+	IsAlive(h EntityHandle) bool                             // handle指向的entity是否还在
+	MarkUnique(ts ...ComponentType) error                    // 把组件类型标记为唯一, 之后别的entity添加这种组件时返回ErrUniqueComponentExists, 创建时panic
+	SetUnique(c Component) Entity                            // 设置唯一组件, 没有entity持有它时创建一个. 返回持有它的entity
+	Unique(t ComponentType) Component                        // 获取唯一组件, 不存在时返回nil
+	UniqueEntity(t ComponentType) Entity                     // 获取持有唯一组件的entity, 不存在时返回nil
+	HasUnique(t ComponentType) bool                          // 唯一组件是否存在
+
+	// SortedGroup 创建一个按compare排序的group, 见NewSortedGroup.
+	// 比较函数没法比较是否相同, 所以每次调用都会创建新的group, 一般在系统初始化时创建一次,
+	// 不再需要时用ReleaseGroup释放, 否则Context会一直更新它.
+	SortedGroup(m Matcher, compare func(a, b Entity) int) Group
+	// ReleaseGroup 让Context不再更新g, 返回g是否属于这个Context. 之后g里的entity保持不变,
+	// 用同一个matcher调用Group会创建新的group.
+	ReleaseGroup(g Group) bool
 }
 
 type pool struct {
-	entityMinID      int
+	entityMinID int
 	// componentsLength ComponentType  // 没啥用
-	entities         map[EntityID]Entity
-	cache            []Entity
-	matcher2group    map[MatcherHash]Group
-	com2groups       map[ComponentType][]Group
-	unused           []Entity
+	entities      map[EntityID]Entity
+	cache         []Entity   // 按ID排好序的entities
+	cacheMu       sync.Mutex // 并行执行的系统可能同时调用Entities()
+	matcher2group map[string]Group
+	groups        []Group // 所有的group, 按创建的顺序, 事件按这个顺序分发
+	com2groups    map[ComponentType][]Group
+	unused        []contextEntity
+	newEntity     func(id int) contextEntity
+	generations   map[EntityID]uint32 // 每个EntityID被分配出去的次数
+	uniqueTypes   map[ComponentType]struct{}
+	uniques       map[ComponentType]Entity // 唯一组件 -> 持有它的entity
+	mu            *contextLock             // 开启WithLocking时才有
+}
+
+type ContextOption func(*pool)
+
+// WithLocking 让Context可以被多个goroutine同时修改.
+// 创建/销毁entity, 增删替换组件, MarkChanged, 注册和删除回调以及创建group都会加同一把锁.
+// entity上的读操作(HasComponent, GetComponent等)不加锁, 读取别的goroutine正在修改的entity需要调用者自己同步.
+// entity和group的回调在解锁之后按事件发生的顺序调用, 所以回调里可以修改这个Context,
+// 但是回调看到的是解锁之后的状态, 可能已经被别的goroutine改过了.
+// matcher和NewSortedGroup的比较函数还是在锁里调用, 不能修改Context.
+func WithLocking() ContextOption {
+	return func(p *pool) {
+		p.mu = &contextLock{}
+	}
 }
 
-func NewContext(startIndex int) Context {
-	return &pool{
+// contextLock 是开启WithLocking时Context和它的entity, group共享的锁.
+// 持有锁时产生的事件先记在pending里, 解锁之后再调用回调.
+type contextLock struct {
+	mu      sync.Mutex
+	pending []func()
+}
+
+func (l *contextLock) lock() {
+	if l != nil {
+		l.mu.Lock()
+	}
+}
+
+// unlock 解锁, 然后调用持有锁期间记下的回调. 回调里再加锁产生的事件由那一次解锁负责.
+func (l *contextLock) unlock() {
+	if l == nil {
+		return
+	}
+	pending := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+// dispatch 记下要在解锁之后调用的回调, 调用者持有锁.
+func (l *contextLock) dispatch(fn func()) {
+	l.pending = append(l.pending, fn)
+}
+
+func NewContext(startIndex int, opts ...ContextOption) Context {
+	p := &pool{
 		entityMinID: startIndex,
 		// componentsLength: componentsLength,
-		entities:         make(map[EntityID]Entity),
-		matcher2group:    make(map[MatcherHash]Group),
-		com2groups:       make(map[ComponentType][]Group),
-		unused:           make([]Entity, 0),
+		entities:      make(map[EntityID]Entity),
+		matcher2group: make(map[string]Group),
+		com2groups:    make(map[ComponentType][]Group),
+		unused:        make([]contextEntity, 0),
+		newEntity: func(id int) contextEntity {
+			return NewEntity(id).(*entity)
+		},
+		generations: make(map[EntityID]uint32),
+		uniqueTypes: make(map[ComponentType]struct{}),
+		uniques:     make(map[ComponentType]Entity),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewArchetypeContext 创建一个按archetype存储组件的Context.
+// 组件集合相同的entity放在同一组chunk里, Add/RemoveComponent时entity会在archetype之间搬家.
+// 省掉的只是每个entity自己的map: GetComponent直接按列下标取chunk里的Component接口值, 不用查map.
+// 组件数据本身不是连续存放的, 读取组件还是要跟一次指针;
+// group的遍历也和NewContext一样, 按ID顺序遍历[]Entity, 不是按chunk的顺序. 这两点都不提供缓存友好的遍历.
+func NewArchetypeContext(startIndex int, opts ...ContextOption) Context {
+	store := newArchetypeStore()
+	p := NewContext(startIndex, opts...).(*pool)
+	p.newEntity = func(id int) contextEntity {
+		return newArchetypeEntity(id, store)
+	}
+	return p
 }
 
 func (p *pool) CreateEntity(cs ...Component) Entity {
-	e := p.getEntity()
-	e.AddComponent(cs...)
+	p.lock()
+	defer p.unlock()
+	return p.mustCreateEntity(cs...)
+}
+
+func (p *pool) mustCreateEntity(cs ...Component) Entity {
+	e, err := p.createEntity(cs...)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (p *pool) createEntity(cs ...Component) (Entity, error) {
+	return p.addEntity(p.getEntity(), cs...)
+}
+
+// tryCreateEntity 和CreateEntity一样, 但是返回错误而不是panic. ctx不是NewContext创建的时候没法提前检查, 直接调用CreateEntity.
+func tryCreateEntity(ctx Context, cs ...Component) (Entity, error) {
+	p, ok := ctx.(*pool)
+	if !ok {
+		return ctx.CreateEntity(cs...), nil
+	}
+	p.lock()
+	defer p.unlock()
+	return p.createEntity(cs...)
+}
+
+// addEntity 先给entity加好组件再加入pool, 组件添加事件不会触发group, 每个group只处理一次entity.
+// 组件被拒绝时entity回到unused里, pool和group都没有被修改.
+func (p *pool) addEntity(e contextEntity, cs ...Component) (Entity, error) {
+	err := e.addComponent(cs...)
+	if err == nil {
+		err = p.validateEntity(e)
+	}
+	if err != nil {
+		e.removeAllComponents()
+		e.removeAllCallbacks()
+		p.unused = append(p.unused, e)
+		return nil, err
+	}
+	p.registerEntity(e)
+	return e, nil
+}
+
+// registerEntity 把已经检查过的entity加入pool, 记录唯一组件, 通知group.
+func (p *pool) registerEntity(e Entity) {
 	p.entities[e.ID()] = e
-	p.cache = append(p.cache, e)
-	for _, g := range p.matcher2group {
+	for t := range p.uniqueTypes {
+		if e.HasComponent(t) {
+			p.uniques[t] = e
+		}
+	}
+	if n := len(p.cache); n > 0 && p.cache[n-1].ID() < e.ID() {
+		p.cache = append(p.cache, e)
+	} else {
+		p.cache = nil
+	}
+	for _, g := range p.groups {
 		g.HandleEntity(e)
 	}
-	return e
+}
+
+// validateEntity 检查还没有加入pool的entity的唯一组件, 再用会匹配e的group的检查函数检查.
+func (p *pool) validateEntity(e Entity) error {
+	for t := range p.uniques {
+		if e.HasComponent(t) {
+			if err := p.validateUnique(e, t); err != nil {
+				return err
+			}
+		}
+	}
+	for _, g := range p.groups {
+		if g.Matches(e) {
+			if err := g.Validate(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateComponent 在已经加入pool的entity添加或者替换组件之前调用, 检查加上c之后的entity.
+// 还没有加入pool的entity由addEntity统一检查.
+func (p *pool) validateComponent(e Entity, c Component) error {
+	if !p.hasEntity(e) {
+		return nil
+	}
+	if err := p.validateUnique(e, c.Type()); err != nil {
+		return err
+	}
+	with := &entityWith{Entity: e, c: c}
+	for _, g := range p.com2groups[c.Type()] {
+		if g.Matches(with) {
+			if err := g.Validate(with); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Instantiate 和CreateEntity一样先加好所有组件再加入group, 每个group只触发一次事件.
+func (p *pool) Instantiate(b *Blueprint, overrides ...Component) Entity {
+	cs := b.components(overrides)
+	p.lock()
+	defer p.unlock()
+	return p.mustCreateEntity(cs...)
 }
 
 func (p *pool) Entities() []Entity {
+	p.lock()
+	defer p.unlock()
+	return p.entitiesLocked()
+}
+
+func (p *pool) entitiesLocked() []Entity {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
 	if p.cache == nil {
-		entities := make([]Entity, len(p.entities))
-		i := 0
+		entities := make([]Entity, 0, len(p.entities))
 		for _, e := range p.entities {
-			entities[i] = e
+			entities = append(entities, e)
 		}
+		sort.Sort(EntitiesByID(entities))
 		p.cache = entities
 	}
 	return p.cache
 }
 
 func (p *pool) Count() int {
+	p.lock()
+	defer p.unlock()
 	return len(p.entities)
 }
 
 func (p *pool) HasEntity(e Entity) bool {
+	p.lock()
+	defer p.unlock()
+	return p.hasEntity(e)
+}
+
+func (p *pool) hasEntity(e Entity) bool {
 	if entity, ok := p.entities[e.ID()]; ok && entity == e {
 		return true
 	}
@@ -68,99 +285,380 @@ func (p *pool) HasEntity(e Entity) bool {
 }
 
 func (p *pool) DestroyEntity(e Entity) {
-	if entity, ok := p.entities[e.ID()]; ok && entity == e {
-		e.RemoveAllComponents()
-		e.RemoveAllCallbacks()
-		delete(p.entities, e.ID())
-		p.cache = nil
-		for _, g := range p.matcher2group {
-			g.HandleEntity(e)
-		}
-		p.unused = append(p.unused, e)
+	p.lock()
+	defer p.unlock()
+	if p.hasEntity(e) {
+		p.destroyEntity(e)
 		return
 	}
 	panic("unknown entity")
 }
 
+// destroyEntity 删除组件, 通知group, 然后把entity放回unused, 下次分配时generation会增加.
+func (p *pool) destroyEntity(e Entity) {
+	ce := e.(contextEntity)
+	ce.removeAllComponents()
+	ce.removeAllCallbacks()
+	delete(p.entities, e.ID())
+	p.cache = nil
+	for _, g := range p.groups {
+		g.HandleEntity(e)
+	}
+	p.unused = append(p.unused, ce)
+}
+
 func (p *pool) DestroyAllEntities() {
-	for _, e := range p.entities {
-		e.RemoveAllComponents()
-		e.RemoveAllCallbacks()
+	p.lock()
+	defer p.unlock()
+	p.destroyAllEntities()
+}
+
+func (p *pool) destroyAllEntities() {
+	for _, e := range p.entitiesLocked() {
+		p.destroyEntity(e)
 	}
-	p.entities = make(map[EntityID]Entity)
-	p.cache = nil
 }
 
 func (p *pool) Group(m Matcher) Group {
-	if g, ok := p.matcher2group[m.Hash()]; ok {
+	p.lock()
+	defer p.unlock()
+	key := matcherKey(m)
+	if g, ok := p.matcher2group[key]; ok {
 		return g
 	}
 
 	g := NewGroup(m)
-	for _, e := range p.entities {
-		g.HandleEntity(e)
+	p.matcher2group[key] = g
+	p.addGroup(g, m)
+	return g
+}
+
+func (p *pool) SortedGroup(m Matcher, compare func(a, b Entity) int) Group {
+	p.lock()
+	defer p.unlock()
+	g := NewSortedGroup(m, compare)
+	p.addGroup(g, m)
+	return g
+}
+
+func (p *pool) ReleaseGroup(g Group) bool {
+	p.lock()
+	defer p.unlock()
+	i := slices.Index(p.groups, g)
+	if i == -1 {
+		return false
 	}
-	p.matcher2group[m.Hash()] = g
+	// 复制一份再删除, 正在分发事件的循环还在遍历旧的slice.
+	p.groups = slices.Delete(slices.Clone(p.groups), i, i+1)
+	for key, cached := range p.matcher2group {
+		if cached == g {
+			delete(p.matcher2group, key)
+		}
+	}
+	for t, groups := range p.com2groups {
+		if i := slices.Index(groups, g); i != -1 {
+			p.com2groups[t] = slices.Delete(slices.Clone(groups), i, i+1)
+		}
+	}
+	return true
+}
 
+func (p *pool) addGroup(g Group, m Matcher) {
+	if g, ok := g.(*group); ok {
+		g.events = p.mu
+	}
+	for _, e := range p.entitiesLocked() {
+		g.HandleEntity(e)
+	}
+	p.groups = append(p.groups, g)
 	for _, component := range m.ComponentTypes() {
 		p.com2groups[component] = append(p.com2groups[component], g)
 	}
+}
 
-	return g
+func (p *pool) Handle(e Entity) EntityHandle {
+	p.lock()
+	defer p.unlock()
+	if !p.hasEntity(e) {
+		return InvalidEntityHandle
+	}
+	return NewEntityHandle(e.ID(), p.generations[e.ID()])
+}
+
+func (p *pool) Resolve(h EntityHandle) (Entity, bool) {
+	p.lock()
+	defer p.unlock()
+	return p.resolve(h)
+}
+
+func (p *pool) resolve(h EntityHandle) (Entity, bool) {
+	if h == InvalidEntityHandle || p.generations[h.ID()] != h.Generation() {
+		return nil, false
+	}
+	e, ok := p.entities[h.ID()]
+	return e, ok
+}
+
+func (p *pool) IsAlive(h EntityHandle) bool {
+	p.lock()
+	defer p.unlock()
+	_, ok := p.resolve(h)
+	return ok
+}
+
+func (p *pool) MarkUnique(ts ...ComponentType) error {
+	p.lock()
+	defer p.unlock()
+	for _, t := range ts {
+		if err := p.markUnique(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markUnique 标记唯一组件并找到已经持有它的entity, 已经有多个entity持有时返回错误.
+func (p *pool) markUnique(t ComponentType) error {
+	if _, ok := p.uniqueTypes[t]; ok {
+		return nil
+	}
+	var holder Entity
+	for _, e := range p.entitiesLocked() {
+		if e.HasComponent(t) {
+			if holder != nil {
+				return fmt.Errorf("%w: %v is held by %v and %v", ErrUniqueComponentExists, t, holder, e)
+			}
+			holder = e
+		}
+	}
+	p.uniqueTypes[t] = struct{}{}
+	if holder != nil {
+		p.uniques[t] = holder
+	}
+	return nil
+}
+
+func (p *pool) SetUnique(c Component) Entity {
+	p.lock()
+	defer p.unlock()
+	if err := p.markUnique(c.Type()); err != nil {
+		panic(err)
+	}
+	if e, ok := p.uniques[c.Type()]; ok {
+		if err := e.(contextEntity).replaceComponent(c); err != nil {
+			panic(err)
+		}
+		return e
+	}
+	return p.mustCreateEntity(c)
+}
+
+func (p *pool) Unique(t ComponentType) Component {
+	p.lock()
+	defer p.unlock()
+	if e, ok := p.uniques[t]; ok {
+		return e.GetComponent(t)
+	}
+	return nil
+}
+
+func (p *pool) UniqueEntity(t ComponentType) Entity {
+	p.lock()
+	defer p.unlock()
+	return p.uniques[t]
+}
+
+func (p *pool) HasUnique(t ComponentType) bool {
+	p.lock()
+	defer p.unlock()
+	_, ok := p.uniques[t]
+	return ok
 }
 
 func (p *pool) String() string {
 	return fmt.Sprintf("Context(%v)", p.Entities())
 }
 
+func (p *pool) lock() {
+	p.mu.lock()
+}
+
+func (p *pool) unlock() {
+	p.mu.unlock()
+}
+
 func (p *pool) componentAddedCallback(e Entity, c Component) {
+	if p.hasEntity(e) {
+		p.addUnique(e, c)
+	}
 	p.forMatchingGroups(e, c, func(g Group) {
 		g.HandleEntity(e)
 	})
 }
 
-func (p *pool) componentReplacedCallback(e Entity, c Component) {
-	p.forMatchingGroups(e, c, func(g Group) {
-		g.UpdateEntity(e)
+func (p *pool) componentReplacedCallback(e Entity, prev, cur Component) {
+	p.forMatchingGroups(e, cur, func(g Group) {
+		g.UpdateEntity(e, prev, cur)
 	})
 }
 
 func (p *pool) componentWillBeRemovedCallback(e Entity, c Component) {
+	without := &entityWithout{Entity: e, t: c.Type()}
 	p.forMatchingGroups(e, c, func(g Group) {
-		delete(e.(*entity).components, c.Type())
-		if !g.Matches(e) {
-			e.(*entity).components[c.Type()] = c
+		if !g.Matches(without) {
 			g.WillRemoveEntity(e)
 		}
 	})
 }
 
 func (p *pool) componentRemovedCallback(e Entity, c Component) {
+	if holder, ok := p.uniques[c.Type()]; ok && holder == e {
+		delete(p.uniques, c.Type())
+	}
 	p.forMatchingGroups(e, c, func(g Group) {
 		g.HandleEntity(e)
 	})
 }
 
-func (p *pool) getEntity() Entity {
-	var e Entity
+// validateUnique 在e得到类型为t的组件之前检查t是不是已经被别的entity持有.
+func (p *pool) validateUnique(e Entity, t ComponentType) error {
+	if holder, ok := p.uniques[t]; ok && holder != e {
+		return fmt.Errorf("%w: %v is held by %v", ErrUniqueComponentExists, t, holder)
+	}
+	return nil
+}
+
+// addUnique 记录已经加入pool的entity持有的唯一组件, 别的entity持有时已经被validateUnique拒绝了.
+func (p *pool) addUnique(e Entity, c Component) {
+	if _, ok := p.uniqueTypes[c.Type()]; ok {
+		p.uniques[c.Type()] = e
+	}
+}
+
+func (p *pool) getEntity() contextEntity {
+	var e contextEntity
 	if len(p.unused) > 0 {
 		e = p.unused[0]
 		p.unused = p.unused[1:]
 	} else {
-		e = NewEntity(p.entityMinID)
+		e = p.newEntity(p.entityMinID)
 		p.entityMinID++
 	}
-	e.AddCallback(ComponentAdded, p.componentAddedCallback)
-	e.AddCallback(ComponentReplaced, p.componentReplacedCallback)
-	e.AddCallback(ComponentWillBeRemoved, p.componentWillBeRemovedCallback)
-	e.AddCallback(ComponentRemoved, p.componentRemovedCallback)
+	p.initEntity(e)
 	return e
 }
 
+// entityWithID 获取指定ID的entity, 用于加载存档时保留原来的ID. 调用者保证ID没有被占用.
+func (p *pool) entityWithID(id EntityID) contextEntity {
+	var e contextEntity
+	for i, u := range p.unused {
+		if u.ID() == id {
+			e = u
+			p.unused = append(p.unused[:i], p.unused[i+1:]...)
+			break
+		}
+	}
+	if e == nil {
+		e = p.newEntity(int(id))
+		if int(id) >= p.entityMinID {
+			p.entityMinID = int(id) + 1
+		}
+	}
+	p.initEntity(e)
+	return e
+}
+
+func (p *pool) initEntity(e contextEntity) {
+	p.generations[e.ID()]++
+	e.setGeneration(p.generations[e.ID()])
+	e.setLock(p.mu)
+	e.setValidator(p.validateComponent)
+	e.addCallback(ComponentAdded, p.componentAddedCallback)
+	e.addReplaceCallback(p.componentReplacedCallback)
+	e.addCallback(ComponentWillBeRemoved, p.componentWillBeRemovedCallback)
+	e.addCallback(ComponentRemoved, p.componentRemovedCallback)
+}
+
 func (p *pool) forMatchingGroups(e Entity, c Component, f func(g Group)) {
-	if p.HasEntity(e) {
+	if p.hasEntity(e) {
 		for _, g := range p.com2groups[c.Type()] {
 			f(g)
 		}
 	}
 }
+
+// entityWithout 是entity去掉某个组件之后的只读视图, 用于判断删除组件之后group是否还匹配.
+type entityWithout struct {
+	Entity
+	t ComponentType
+}
+
+func (e *entityWithout) HasComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if t == e.t {
+			return false
+		}
+	}
+	return e.Entity.HasComponent(ts...)
+}
+
+func (e *entityWithout) HasAnyComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if t != e.t && e.Entity.HasComponent(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *entityWithout) Component(t ComponentType) (Component, error) {
+	if t == e.t {
+		return nil, ErrComponentDoesNotExist
+	}
+	return e.Entity.Component(t)
+}
+
+func (e *entityWithout) GetComponent(t ComponentType) Component {
+	if t == e.t {
+		return nil
+	}
+	return e.Entity.GetComponent(t)
+}
+
+// entityWith 是entity添加或者替换组件c之后的只读视图, 用于在修改entity之前检查.
+type entityWith struct {
+	Entity
+	c Component
+}
+
+func (e *entityWith) HasComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if t != e.c.Type() && !e.Entity.HasComponent(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entityWith) HasAnyComponent(ts ...ComponentType) bool {
+	for _, t := range ts {
+		if t == e.c.Type() || e.Entity.HasComponent(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *entityWith) Component(t ComponentType) (Component, error) {
+	if t == e.c.Type() {
+		return e.c, nil
+	}
+	return e.Entity.Component(t)
+}
+
+func (e *entityWith) GetComponent(t ComponentType) Component {
+	if t == e.c.Type() {
+		return e.c
+	}
+	return e.Entity.GetComponent(t)
+}
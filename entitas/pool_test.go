@@ -1,14 +1,18 @@
 package entitas
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestContext(t *testing.T) {
 	Convey("Given a new pool", t, func() {
-		p := NewContext(NumComponents, 0)
+		p := NewContext(0)
 
 		Convey("It increments creationIndex", func() {
 			So(p.CreateEntity().ID(), ShouldEqual, 0)
@@ -16,7 +20,7 @@ func TestContext(t *testing.T) {
 		})
 
 		Convey("It starts with given creationIndex", func() {
-			So(NewContext(NumComponents, 42).CreateEntity().ID(), ShouldEqual, 42)
+			So(NewContext(42).CreateEntity().ID(), ShouldEqual, 42)
 		})
 
 		Convey("It has no entities when no entities were created", func() {
@@ -50,6 +54,22 @@ func TestContext(t *testing.T) {
 			So(len(entities), ShouldEqual, 2)
 		})
 
+		Convey("It returns entities ordered by ID", func() {
+			e0 := p.CreateEntity()
+			e1 := p.CreateEntity()
+			e2 := p.CreateEntity()
+			So(p.Entities(), ShouldResemble, []Entity{e0, e1, e2})
+			p.DestroyEntity(e1)
+			p.DestroyEntity(e0)
+			So(p.Entities(), ShouldResemble, []Entity{e2})
+			// 被销毁的entity会按销毁的顺序被重用, ID也会被重用
+			e3 := p.CreateEntity()
+			e4 := p.CreateEntity()
+			So(e3.ID(), ShouldEqual, 1)
+			So(e4.ID(), ShouldEqual, 0)
+			So(p.Entities(), ShouldResemble, []Entity{e4, e3, e2})
+		})
+
 		Convey("It destroys entity and removes it", func() {
 			e := p.CreateEntity()
 			p.DestroyEntity(e)
@@ -157,6 +177,20 @@ func TestContext(t *testing.T) {
 				So(p.Group(matcher), ShouldEqual, p.Group(matcher))
 			})
 
+			Convey("It gets cached group for an equal matcher", func() {
+				So(p.Group(AllOf(ComponentB, ComponentA)), ShouldEqual, p.Group(matcher))
+				m := NewMatcher().AllOf(ComponentA, ComponentB)
+				So(p.Group(NewMatcher().AllOf(ComponentB).AllOf(ComponentA)), ShouldEqual, p.Group(m))
+			})
+
+			Convey("It doesn't mix up groups of different matchers", func() {
+				// 以前的XOR hash中这两个matcher会碰撞.
+				anyC := AnyOf(ComponentC)
+				So(p.Group(anyC), ShouldNotEqual, p.Group(matcher))
+				So(p.Group(anyC).Entities(), ShouldBeEmpty)
+				So(len(p.Group(matcher).Entities()), ShouldEqual, 2)
+			})
+
 			Convey("It cached group contains newly created matching entity", func() {
 				g := p.Group(matcher)
 				eA.AddComponent(NewComponentB(3.3))
@@ -279,6 +313,59 @@ func TestContext(t *testing.T) {
 			})
 		})
 
+		Convey("Given a group created using a value matcher", func() {
+			positive := Where(ComponentA, func(c Component) bool { return c.(*componentA).value > 0 })
+			group := p.Group(AllOf(positive, ComponentB))
+			e := p.CreateEntity(NewComponentA(1), NewComponentB(1.1))
+
+			Convey("It adds entity when the value matches", func() {
+				So(group.Entities(), ShouldContain, e)
+				So(p.Group(AllOf(ComponentB, positive)), ShouldEqual, group)
+			})
+
+			Convey("It removes entity when a replaced value doesn't match anymore", func() {
+				removed := 0
+				group.AddCallback(EntityRemoved, func(g Group, e Entity) { removed++ })
+				group.AddUpdateCallback(func(g Group, e Entity, prev, cur Component) { t.Fail() })
+				e.ReplaceComponent(NewComponentA(-1))
+				So(group.Entities(), ShouldNotContain, e)
+				So(removed, ShouldEqual, 1)
+			})
+
+			Convey("It adds entity when a replaced value starts to match", func() {
+				other := p.CreateEntity(NewComponentA(0), NewComponentB(2.2))
+				So(group.Entities(), ShouldNotContain, other)
+				added := 0
+				group.AddCallback(EntityAdded, func(g Group, e Entity) { added++ })
+				other.ReplaceComponent(NewComponentA(2))
+				So(group.Entities(), ShouldContain, other)
+				So(added, ShouldEqual, 1)
+			})
+
+			Convey("It updates entity when a replaced value still matches", func() {
+				updated := 0
+				group.AddUpdateCallback(func(g Group, e Entity, prev, cur Component) { updated++ })
+				e.ReplaceComponent(NewComponentA(2))
+				So(group.Entities(), ShouldContain, e)
+				So(updated, ShouldEqual, 1)
+			})
+
+			Convey("It keeps groups of different predicates apart", func() {
+				negative := Where(ComponentA, func(c Component) bool { return c.(*componentA).value < 0 })
+				other := p.Group(AllOf(negative, ComponentB))
+				So(other, ShouldNotEqual, group)
+				So(other.Entities(), ShouldBeEmpty)
+			})
+
+			Convey("It will remove entity when the component is removed", func() {
+				willRemove := 0
+				group.AddCallback(EntityWillBeRemoved, func(g Group, e Entity) { willRemove++ })
+				e.RemoveComponent(ComponentA)
+				So(willRemove, ShouldEqual, 1)
+				So(group.Entities(), ShouldNotContain, e)
+			})
+		})
+
 		Convey("Given an AllOf containing a NoneOf", func() {
 			allOfAB := AllOf(ComponentA, ComponentB)
 			noneOfC := NoneOf(ComponentC)
@@ -337,7 +424,7 @@ func TestContext(t *testing.T) {
 }
 
 func BenchmarkContextCreateGroup(b *testing.B) {
-	p := NewContext(NumComponents, 0)
+	p := NewContext(0)
 
 	for i := 0; i < 2000; i++ {
 		p.CreateEntity(
@@ -352,3 +439,197 @@ func BenchmarkContextCreateGroup(b *testing.B) {
 		g.Entities()
 	}
 }
+
+// storages 返回用两种存储方式创建的Context, 按名字索引. 需要覆盖两种存储的测试都用它.
+func storages(opts ...ContextOption) map[string]Context {
+	return map[string]Context{
+		"map":       NewContext(0, opts...),
+		"archetype": NewArchetypeContext(0, opts...),
+	}
+}
+
+func TestContextWithLocking(t *testing.T) {
+	Convey("Given a pool with locking", t, func() {
+		for name, p := range storages(WithLocking()) {
+			groupA := p.Group(AllOf(ComponentA))
+			groupAB := p.Group(AllOf(ComponentA, ComponentB))
+			observer := NewGroupObserver(groupAB, ObserverEntityAdded)
+
+			Convey(fmt.Sprintf("It handles concurrent writers (%s)", name), func() {
+				const writers = 8
+				const perWriter = 200
+				var wg sync.WaitGroup
+				done := make(chan struct{})
+
+				go func() {
+					for {
+						select {
+						case <-done:
+							return
+						default:
+							p.Entities()
+							p.Count()
+							groupA.Entities()
+							observer.CollectedEntities()
+						}
+					}
+				}()
+
+				for w := 0; w < writers; w++ {
+					wg.Add(1)
+					go func(w int) {
+						defer wg.Done()
+						for i := 0; i < perWriter; i++ {
+							e := p.CreateEntity(NewComponentA(i))
+							e.AddComponent(NewComponentB(float32(i)))
+							e.ReplaceComponent(NewComponentA(-i))
+							if i%2 == 0 {
+								e.RemoveComponent(ComponentB)
+							}
+							if i%4 == 0 {
+								p.DestroyEntity(e)
+							}
+						}
+					}(w)
+				}
+				wg.Wait()
+				close(done)
+
+				So(p.Count(), ShouldEqual, writers*perWriter*3/4)
+				So(len(groupA.Entities()), ShouldEqual, writers*perWriter*3/4)
+				So(len(groupAB.Entities()), ShouldEqual, writers*perWriter/2)
+			})
+
+			Convey(fmt.Sprintf("Callbacks can modify the context (%s)", name), func() {
+				var addErr error
+				groupA.AddCallback(EntityAdded, func(g Group, e Entity) {
+					addErr = e.AddComponent(NewComponentB(1))
+					e.AddCallback(ComponentRemoved, func(e Entity, c Component) {
+						if p.HasEntity(e) {
+							p.DestroyEntity(e)
+						}
+					})
+				})
+				groupAB.AddUpdateCallback(func(g Group, e Entity, prev, cur Component) {
+					p.CreateEntity(NewComponentC())
+				})
+
+				done := make(chan Entity)
+				go func() {
+					e := p.CreateEntity(NewComponentA(1))
+					e.ReplaceComponent(NewComponentB(2))
+					e.RemoveComponent(ComponentB)
+					done <- e
+				}()
+				select {
+				case e := <-done:
+					So(addErr, ShouldBeNil)
+					So(p.HasEntity(e), ShouldBeFalse)
+					So(p.Count(), ShouldEqual, 1)
+					So(p.Entities()[0].HasComponent(ComponentC), ShouldBeTrue)
+				case <-time.After(5 * time.Second):
+					So("deadlock", ShouldBeEmpty)
+				}
+			})
+		}
+	})
+}
+
+func TestUniqueComponents(t *testing.T) {
+	Convey("Given a pool", t, func() {
+		p := NewContext(0)
+
+		Convey("Unique components are absent by default", func() {
+			So(p.HasUnique(ComponentA), ShouldBeFalse)
+			So(p.Unique(ComponentA), ShouldBeNil)
+			So(p.UniqueEntity(ComponentA), ShouldBeNil)
+		})
+
+		Convey("When a unique component is set", func() {
+			g := p.Group(AllOf(ComponentA))
+			observer := NewGroupObserver(g, ObserverEntityAdded)
+			e := p.SetUnique(NewComponentA(1))
+
+			Convey("It can be looked up", func() {
+				So(p.HasUnique(ComponentA), ShouldBeTrue)
+				So(p.Unique(ComponentA).(*componentA).value, ShouldEqual, 1)
+				So(p.UniqueEntity(ComponentA), ShouldEqual, e)
+			})
+
+			Convey("Groups and observers see it", func() {
+				So(g.Entities(), ShouldResemble, []Entity{e})
+				So(observer.CollectedEntities(), ShouldResemble, []Entity{e})
+			})
+
+			Convey("Setting it again replaces the component on the same entity", func() {
+				So(p.SetUnique(NewComponentA(2)), ShouldEqual, e)
+				So(p.Unique(ComponentA).(*componentA).value, ShouldEqual, 2)
+				So(p.Count(), ShouldEqual, 1)
+			})
+
+			Convey("Another entity can't be created with it", func() {
+				So(func() { p.CreateEntity(NewComponentA(2)) }, ShouldPanic)
+				So(p.Count(), ShouldEqual, 1)
+				So(g.Entities(), ShouldResemble, []Entity{e})
+				So(p.UniqueEntity(ComponentA), ShouldEqual, e)
+			})
+
+			Convey("Another entity can't add or replace it", func() {
+				other := p.CreateEntity()
+				err := other.AddComponent(NewComponentA(2))
+				So(errors.Is(err, ErrUniqueComponentExists), ShouldBeTrue)
+				err = other.ReplaceComponent(NewComponentA(2))
+				So(errors.Is(err, ErrUniqueComponentExists), ShouldBeTrue)
+				So(other.HasComponent(ComponentA), ShouldBeFalse)
+				So(g.Entities(), ShouldResemble, []Entity{e})
+				So(p.UniqueEntity(ComponentA), ShouldEqual, e)
+			})
+
+			Convey("It can move to another entity after being removed", func() {
+				e.RemoveComponent(ComponentA)
+				So(p.HasUnique(ComponentA), ShouldBeFalse)
+				other := p.CreateEntity(NewComponentA(3))
+				So(p.UniqueEntity(ComponentA), ShouldEqual, other)
+			})
+
+			Convey("It is gone after the entity is destroyed", func() {
+				p.DestroyEntity(e)
+				So(p.HasUnique(ComponentA), ShouldBeFalse)
+			})
+		})
+
+		Convey("Marking a type unique finds the existing holder", func() {
+			e := p.CreateEntity(NewComponentB(1))
+			So(p.MarkUnique(ComponentB), ShouldBeNil)
+			So(p.UniqueEntity(ComponentB), ShouldEqual, e)
+		})
+
+		Convey("Marking a type carried by several entities fails", func() {
+			p.CreateEntity(NewComponentB(1))
+			p.CreateEntity(NewComponentB(2))
+			err := p.MarkUnique(ComponentB)
+			So(errors.Is(err, ErrUniqueComponentExists), ShouldBeTrue)
+		})
+	})
+}
+
+func TestReleaseGroup(t *testing.T) {
+	Convey("Given a cached group of a pool", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+
+		Convey("Releasing it drops it from the cache", func() {
+			So(p.ReleaseGroup(g), ShouldBeTrue)
+			e := p.CreateEntity(NewComponentA(1))
+			So(g.Entities(), ShouldBeEmpty)
+			again := p.Group(AllOf(ComponentA))
+			So(again, ShouldNotEqual, g)
+			So(again.Entities(), ShouldResemble, []Entity{e})
+		})
+
+		Convey("Groups of other pools can't be released", func() {
+			So(NewContext(0).ReleaseGroup(g), ShouldBeFalse)
+			So(p.ReleaseGroup(NewGroup(AllOf(ComponentA))), ShouldBeFalse)
+		})
+	})
+}
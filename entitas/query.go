@@ -0,0 +1,348 @@
+package entitas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError 是ParseMatcher遇到的错误.
+type ParseError struct {
+	Pos int    // 出错的位置, 从0开始的字节偏移
+	Msg string // 错误描述
+	Err error  // 组件名没有注册时是ErrComponentNotRegistered
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("entitas: parse matcher at %d: %s", e.Pos, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseMatcher 用DefaultRegistry里的组件名解析matcher, 见ComponentRegistry.ParseMatcher.
+func ParseMatcher(s string) (Matcher, error) {
+	return DefaultRegistry.ParseMatcher(s)
+}
+
+// ParseMatcher 把文本解析成matcher, 组件用r里注册的名字或者ComponentType的数字表示:
+//
+//	all(Position, Velocity) & any(Player, Npc) & !Dead
+//
+// 支持的写法:
+//
+//	a & b          AllOf(a, b)
+//	a | b          AnyOf(a, b), 优先级比&低
+//	!a             NoneOf(a)
+//	(a | b) & c    括号
+//	all(a, b)      AllOf, any和none同理, 也可以写成AllOf, AnyOf, NoneOf
+//	NewMatcher().AllOf(a, b).NoneOf(c)
+//
+// AllOf, AnyOf, NoneOf, NewMatcher和组件类型的String()都可以被解析回相等的matcher,
+// 组件名用的是DefaultRegistry. Where和自定义的matcher不能被解析.
+func (r *ComponentRegistry) ParseMatcher(s string) (Matcher, error) {
+	p := &matcherParser{r: r, src: s}
+	if err := p.scan(); err != nil {
+		return nil, err
+	}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %v", p.tok)
+	}
+	return m, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+type matcherParser struct {
+	r    *ComponentRegistry
+	src  string
+	tok  token // 当前的token
+	next int   // 下一个token开始扫描的位置
+}
+
+func (p *matcherParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *matcherParser) scan() error {
+	for p.next < len(p.src) && isSpace(p.src[p.next]) {
+		p.next++
+	}
+	start := p.next
+	kind := tokenPunct
+	switch {
+	case start == len(p.src):
+		kind = tokenEOF
+	case isLetter(p.src[start]):
+		kind = tokenIdent
+		for p.next < len(p.src) && (isLetter(p.src[p.next]) || isDigit(p.src[p.next])) {
+			p.next++
+		}
+	case isDigit(p.src[start]):
+		kind = tokenNumber
+		for p.next < len(p.src) && isDigit(p.src[p.next]) {
+			p.next++
+		}
+	case strings.IndexByte("(),&|!.", p.src[start]) >= 0:
+		p.next++
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[start:])
+		return &ParseError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+	}
+	p.tok = token{kind: kind, text: p.src[start:p.next], pos: start}
+	return nil
+}
+
+func (p *matcherParser) is(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.text == punct
+}
+
+func (p *matcherParser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.errorf("expected %q, found %v", punct, p.tok)
+	}
+	return p.scan()
+}
+
+// or 解析 and ('|' and)*.
+func (p *matcherParser) or() (Matcher, error) {
+	return p.binary("|", p.and, AnyOf)
+}
+
+// and 解析 unary ('&' unary)*.
+func (p *matcherParser) and() (Matcher, error) {
+	return p.binary("&", p.unary, AllOf)
+}
+
+func (p *matcherParser) binary(op string, operand func() (Matcher, error), combine func(...Matcher) Matcher) (Matcher, error) {
+	m, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	ms := []Matcher{m}
+	for p.is(op) {
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		m, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	if len(ms) == 1 {
+		return ms[0], nil
+	}
+	return combine(ms...), nil
+}
+
+// unary 解析 '!' unary | primary.
+func (p *matcherParser) unary() (Matcher, error) {
+	if !p.is("!") {
+		return p.primary()
+	}
+	if err := p.scan(); err != nil {
+		return nil, err
+	}
+	m, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return NoneOf(m), nil
+}
+
+// primary 解析组件, 函数调用或者括号.
+func (p *matcherParser) primary() (Matcher, error) {
+	switch {
+	case p.tok.kind == tokenIdent:
+		name := p.tok
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		if p.is("(") {
+			return p.call(name)
+		}
+		t, err := p.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case p.tok.kind == tokenNumber:
+		t, err := p.component()
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case p.is("("):
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return m, p.expect(")")
+	}
+	return nil, p.errorf("expected component or matcher, found %v", p.tok)
+}
+
+// call 解析函数名后面的参数, 当前token是'('.
+func (p *matcherParser) call(name token) (Matcher, error) {
+	var combine func(...Matcher) Matcher
+	switch name.text {
+	case "all", "AllOf":
+		combine = AllOf
+	case "any", "AnyOf":
+		combine = AnyOf
+	case "none", "NoneOf":
+		combine = NoneOf
+	case "NewMatcher":
+		return p.compound()
+	default:
+		return nil, &ParseError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var ms []Matcher
+	for !p.is(")") {
+		if len(ms) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	if err := p.scan(); err != nil {
+		return nil, err
+	}
+	return combine(ms...), nil
+}
+
+// compound 解析 NewMatcher() ('.' ('AllOf'|'AnyOf'|'NoneOf') '(' 组件列表 ')')*, 当前token是'('.
+func (p *matcherParser) compound() (Matcher, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	m := NewMatcher()
+	for p.is(".") {
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		var add func(...ComponentType) *CompoundMatcher
+		switch p.tok.text {
+		case "AllOf":
+			add = m.AllOf
+		case "AnyOf":
+			add = m.AnyOf
+		case "NoneOf":
+			add = m.NoneOf
+		default:
+			return nil, p.errorf("expected AllOf, AnyOf or NoneOf, found %v", p.tok)
+		}
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var ts []ComponentType
+		for !p.is(")") {
+			if len(ts) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			var t ComponentType
+			var err error
+			switch p.tok.kind {
+			case tokenIdent:
+				name := p.tok
+				if err := p.scan(); err != nil {
+					return nil, err
+				}
+				t, err = p.lookup(name)
+			case tokenNumber:
+				t, err = p.component()
+			default:
+				err = p.errorf("expected component, found %v", p.tok)
+			}
+			if err != nil {
+				return nil, err
+			}
+			ts = append(ts, t)
+		}
+		if err := p.scan(); err != nil {
+			return nil, err
+		}
+		m = add(ts...)
+	}
+	return m, nil
+}
+
+func (p *matcherParser) lookup(name token) (ComponentType, error) {
+	info, ok := p.r.Lookup(name.text)
+	if !ok {
+		return 0, &ParseError{Pos: name.pos, Msg: fmt.Sprintf("unknown component %q", name.text), Err: ErrComponentNotRegistered}
+	}
+	return info.Type, nil
+}
+
+// component 解析用数字表示的组件.
+func (p *matcherParser) component() (ComponentType, error) {
+	n, err := strconv.ParseUint(p.tok.text, 10, 16)
+	if err != nil {
+		return 0, p.errorf("component type %s out of range", p.tok.text)
+	}
+	return ComponentType(n), p.scan()
+}
+
+func isSpace(c byte) bool  { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+func isLetter(c byte) bool { return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+func isDigit(c byte) bool  { return '0' <= c && c <= '9' }
+
+// isIdentifier 判断name能不能在ParseMatcher里作为组件名.
+func isIdentifier(name string) bool {
+	if name == "" || !isLetter(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) {
+			return false
+		}
+	}
+	return true
+}
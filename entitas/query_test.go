@@ -0,0 +1,119 @@
+package entitas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type queryVelocity struct{ X, Y float64 }
+
+func (*queryVelocity) Type() ComponentType { return queryVelocityType }
+
+type queryDead struct{}
+
+func (*queryDead) Type() ComponentType { return queryDeadType }
+
+var (
+	queryVelocityType = RegisterComponentAs[*queryVelocity](114, "Velocity")
+	queryDeadType     = RegisterComponentAs[*queryDead](115, "Dead")
+)
+
+func TestParseMatcher(t *testing.T) {
+	Convey("Subject: Parsing matchers", t, func() {
+		r := NewComponentRegistry()
+		r.RegisterAs(0, reflect.TypeFor[*componentA](), "Position")
+		r.RegisterAs(1, reflect.TypeFor[*componentB](), "Velocity")
+		r.RegisterAs(2, reflect.TypeFor[*componentC](), "Player")
+		r.RegisterAs(3, reflect.TypeFor[*componentD](), "Npc")
+		r.RegisterAs(4, reflect.TypeFor[*componentE](), "Dead")
+
+		parse := func(s string) Matcher {
+			m, err := r.ParseMatcher(s)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("It builds AllOf, AnyOf and NoneOf trees", func() {
+			m := parse("all(Position, Velocity) & any(Player, Npc) & !Dead")
+			expected := AllOf(AllOf(ComponentA, ComponentB), AnyOf(ComponentC, ComponentD), NoneOf(ComponentE))
+			So(m.Equals(expected), ShouldBeTrue)
+		})
+
+		Convey("It binds & tighter than |", func() {
+			So(parse("Position | Velocity & Player").Equals(AnyOf(ComponentA, AllOf(ComponentB, ComponentC))), ShouldBeTrue)
+			So(parse("(Position | Velocity) & Player").Equals(AllOf(AnyOf(ComponentA, ComponentB), ComponentC)), ShouldBeTrue)
+			So(parse("!Position & Velocity").Equals(AllOf(NoneOf(ComponentA), ComponentB)), ShouldBeTrue)
+			So(parse("!(Position | Velocity)").Equals(NoneOf(AnyOf(ComponentA, ComponentB))), ShouldBeTrue)
+		})
+
+		Convey("It accepts component types as numbers", func() {
+			So(parse("AllOf(0, Velocity)").Equals(AllOf(ComponentA, ComponentB)), ShouldBeTrue)
+			So(parse(" 3 ").Equals(ComponentD), ShouldBeTrue)
+			So(parse("AnyOf()").Equals(AnyOf()), ShouldBeTrue)
+		})
+
+		Convey("It parses compound matchers", func() {
+			m := parse("NewMatcher().AllOf(Velocity, 0).NoneOf(Dead)")
+			So(m.Equals(NewMatcher().AllOf(ComponentA, ComponentB).NoneOf(ComponentE)), ShouldBeTrue)
+			So(parse("NewMatcher()").Equals(NewMatcher()), ShouldBeTrue)
+		})
+
+		Convey("It reports errors with positions", func() {
+			cases := []struct {
+				src string
+				pos int
+			}{
+				{"", 0},
+				{"Position &", 10},
+				{"all(Position Velocity)", 13},
+				{"all(Position", 12},
+				{"Position)", 8},
+				{"Position # Velocity", 9},
+				{"some(Position)", 0},
+				{"70000", 0},
+				{"NewMatcher().SomeOf(Position)", 13},
+				{"NewMatcher().AllOf(all(Position))", 19},
+			}
+			for _, c := range cases {
+				_, err := r.ParseMatcher(c.src)
+				var perr *ParseError
+				So(errors.As(err, &perr), ShouldBeTrue)
+				So(perr.Pos, ShouldEqual, c.pos)
+			}
+		})
+
+		Convey("It reports unknown components", func() {
+			_, err := r.ParseMatcher("Position & Health")
+			So(errors.Is(err, ErrComponentNotRegistered), ShouldBeTrue)
+			So(err.(*ParseError).Pos, ShouldEqual, 11)
+			So(err.Error(), ShouldEqual, `entitas: parse matcher at 11: unknown component "Health"`)
+		})
+	})
+
+	Convey("Subject: Printing and parsing matchers", t, func() {
+		Convey("Registered components are printed by name", func() {
+			So(queryVelocityType.String(), ShouldEqual, "Velocity")
+			So(ComponentA.String(), ShouldEqual, "0")
+		})
+
+		Convey("Printed matchers can be parsed again", func() {
+			matchers := []Matcher{
+				queryDeadType,
+				AllOf(queryVelocityType, ComponentA),
+				AnyOf(AllOf(queryVelocityType, ComponentB), NoneOf(queryDeadType), ComponentC),
+				NoneOf(AnyOf(), AllOf(ComponentA)),
+				NewMatcher().AllOf(queryVelocityType).AnyOf(ComponentA, ComponentB).NoneOf(queryDeadType),
+				AllOf(NewMatcher(), NewMatcher().NoneOf(ComponentF)),
+			}
+			for _, m := range matchers {
+				parsed, err := ParseMatcher(m.String())
+				So(err, ShouldBeNil)
+				So(parsed.Equals(m), ShouldBeTrue)
+				So(parsed.String(), ShouldEqual, m.String())
+			}
+		})
+	})
+}
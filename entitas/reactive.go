@@ -0,0 +1,93 @@
+package entitas
+
+import "sort"
+
+// Trigger 描述ReactiveSystem关心的group事件.
+type Trigger struct {
+	Group Group
+	Event ObserverEvent
+}
+
+// ReactiveSystem 不需要每帧遍历整个group, 只处理上次执行以来触发过Triggers()的entity.
+// World在Execute阶段把收集到的entity按ID排序后传给Execute, 然后清空收集器.
+// 收集之后被销毁或者回收再利用的entity会被丢掉, 所以Triggers()的group要来自World的Context.
+// 没有收集到entity时不会调用Execute.
+type ReactiveSystem interface {
+	Triggers() []Trigger
+	Execute(entities []Entity)
+}
+
+// ReactiveFilter 是ReactiveSystem的可选接口, 返回false的entity不会传给Execute.
+type ReactiveFilter interface {
+	Filter(e Entity) bool
+}
+
+// collector 把多个GroupObserver收集到的entity合并起来, 用ctx检查收集时的handle是否还有效.
+type collector struct {
+	ctx       Context
+	observers []*groupObserver
+}
+
+func newCollector(ctx Context, triggers []Trigger) *collector {
+	c := &collector{ctx: ctx}
+	for _, t := range triggers {
+		c.observers = append(c.observers, NewGroupObserver(t.Group, t.Event))
+	}
+	return c
+}
+
+func (c *collector) activate() {
+	for _, o := range c.observers {
+		o.Activate()
+	}
+}
+
+func (c *collector) deactivate() {
+	for _, o := range c.observers {
+		o.Deactivate()
+	}
+}
+
+func (c *collector) dispose() {
+	for _, o := range c.observers {
+		o.Dispose()
+	}
+}
+
+// drain 返回收集到, 仍然存活并且通过filter的entity, 然后清空收集器.
+func (c *collector) drain(filter func(Entity) bool) []Entity {
+	var handles []EntityHandle
+	var collected []Entity
+	seen := make(map[Entity]int)
+	for _, o := range c.observers {
+		o.forEachHandle(func(e Entity, h EntityHandle) {
+			if i, ok := seen[e]; !ok {
+				seen[e] = len(collected)
+				collected = append(collected, e)
+				handles = append(handles, h)
+			} else if h.Generation() > handles[i].Generation() {
+				handles[i] = h // 不同observer收集到了entity的不同生命周期, 以最新的为准
+			}
+		})
+		o.ClearCollectedEntities()
+	}
+	var entities []Entity
+	for i, e := range collected {
+		if c.ctx.IsAlive(handles[i]) && (filter == nil || filter(e)) {
+			entities = append(entities, e)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].ID() < entities[j].ID() })
+	return entities
+}
+
+func (e *systemEntry) react() {
+	s := e.system.(ReactiveSystem)
+	var filter func(Entity) bool
+	if f, ok := s.(ReactiveFilter); ok {
+		filter = f.Filter
+	}
+	if entities := e.collector.drain(filter); len(entities) > 0 {
+		s.Execute(entities)
+	}
+}
@@ -0,0 +1,127 @@
+package entitas
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type reactiveTestSystem struct {
+	ctx      Context
+	event    ObserverEvent
+	filter   func(Entity) bool
+	executed [][]Entity
+}
+
+func (s *reactiveTestSystem) Triggers() []Trigger {
+	return []Trigger{{Group: s.ctx.Group(AllOf(ComponentA)), Event: s.event}}
+}
+
+func (s *reactiveTestSystem) Execute(entities []Entity) {
+	s.executed = append(s.executed, entities)
+}
+
+type filteredReactiveTestSystem struct {
+	reactiveTestSystem
+}
+
+func (s *filteredReactiveTestSystem) Filter(e Entity) bool {
+	return s.filter(e)
+}
+
+func TestReactiveSystem(t *testing.T) {
+	Convey("Given a world with a reactive system", t, func() {
+		p := NewContext(0)
+		w := NewWorld(p)
+		s := &reactiveTestSystem{ctx: p, event: ObserverEntityAdded}
+		w.AddSystem(s)
+		w.Initialize()
+
+		Convey("It doesn't execute when nothing was collected", func() {
+			w.Tick(time.Second)
+			So(s.executed, ShouldBeEmpty)
+		})
+
+		Convey("It executes with the collected entities sorted by ID", func() {
+			e2 := p.CreateEntity()
+			e1 := p.CreateEntity(NewComponentA(1))
+			p.CreateEntity(NewComponentB(1))
+			e2.AddComponent(NewComponentA(2))
+			w.Tick(time.Second)
+			So(s.executed, ShouldResemble, [][]Entity{{e2, e1}})
+		})
+
+		Convey("It clears the collector after each tick", func() {
+			p.CreateEntity(NewComponentA(1))
+			w.Tick(time.Second)
+			w.Tick(time.Second)
+			So(len(s.executed), ShouldEqual, 1)
+		})
+
+		Convey("It drops entities destroyed or recycled after they were collected", func() {
+			destroyed := p.CreateEntity(NewComponentA(1))
+			recycled := p.CreateEntity(NewComponentA(2))
+			kept := p.CreateEntity(NewComponentA(3))
+			p.DestroyEntity(recycled)
+			So(p.CreateEntity(NewComponentB(1)), ShouldEqual, recycled)
+			p.DestroyEntity(destroyed)
+			w.Tick(time.Second)
+			So(s.executed, ShouldResemble, [][]Entity{{kept}})
+		})
+
+		Convey("It passes a recycled entity that triggered again", func() {
+			e := p.CreateEntity(NewComponentA(1))
+			p.DestroyEntity(e)
+			So(p.CreateEntity(NewComponentA(2)), ShouldEqual, e)
+			w.Tick(time.Second)
+			So(s.executed, ShouldResemble, [][]Entity{{e}})
+		})
+
+		Convey("It doesn't collect while disabled", func() {
+			w.Disable(s)
+			p.CreateEntity(NewComponentA(1))
+			w.Enable(s)
+			w.Tick(time.Second)
+			So(s.executed, ShouldBeEmpty)
+		})
+
+		Convey("It detaches from its groups on tear down", func() {
+			g := p.Group(AllOf(ComponentA)).(*group)
+			before := len(g.callbacks[EntityAdded])
+			w.TearDown()
+			So(len(g.callbacks[EntityAdded]), ShouldEqual, before-1)
+
+			w.Initialize()
+			So(len(g.callbacks[EntityAdded]), ShouldEqual, before)
+			p.CreateEntity(NewComponentA(1))
+			w.Tick(time.Second)
+			So(len(s.executed), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a world with a filtered reactive system", t, func() {
+		p := NewContext(0)
+		w := NewWorld(p)
+		s := &filteredReactiveTestSystem{reactiveTestSystem{
+			ctx:    p,
+			event:  ObserverEntityAddedOrRemoved,
+			filter: func(e Entity) bool { return e.HasComponent(ComponentB) },
+		}}
+		w.AddSystem(s)
+
+		Convey("It only passes entities accepted by the filter", func() {
+			w.Initialize()
+			p.CreateEntity(NewComponentA(1))
+			e := p.CreateEntity(NewComponentA(2), NewComponentB(2))
+			w.Tick(time.Second)
+			So(s.executed, ShouldResemble, [][]Entity{{e}})
+		})
+
+		Convey("It starts collecting when the world is initialized", func() {
+			p.CreateEntity(NewComponentA(1), NewComponentB(1))
+			w.Tick(time.Second)
+			So(s.executed, ShouldBeEmpty)
+		})
+	})
+}
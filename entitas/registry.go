@@ -0,0 +1,180 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrComponentRegistered = errors.New("component already registered")
+	ErrComponentTypeTaken  = errors.New("component type already registered")
+	ErrComponentNameTaken  = errors.New("component name already registered")
+)
+
+// ComponentInfo 描述一个注册过的组件.
+type ComponentInfo struct {
+	Type   ComponentType
+	Name   string
+	GoType reflect.Type
+}
+
+// ComponentRegistry 负责分配ComponentType, 并记录组件的Go类型和名字.
+type ComponentRegistry struct {
+	mu     sync.Mutex
+	byType map[ComponentType]ComponentInfo
+	byName map[string]ComponentInfo
+	byGo   atomic.Pointer[map[reflect.Type]ComponentType] // 只读快照, 注册时整体替换, 查询不加锁
+	next   ComponentType
+}
+
+// DefaultRegistry 是RegisterComponent和ComponentOf使用的注册表.
+var DefaultRegistry = NewComponentRegistry()
+
+func NewComponentRegistry() *ComponentRegistry {
+	r := &ComponentRegistry{
+		byType: make(map[ComponentType]ComponentInfo),
+		byName: make(map[string]ComponentInfo),
+	}
+	r.byGo.Store(&map[reflect.Type]ComponentType{})
+	return r
+}
+
+// Register 用下一个空闲的ComponentType注册组件.
+func (r *ComponentRegistry) Register(goType reflect.Type, name string) (ComponentType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if _, ok := r.byType[r.next]; !ok {
+			break
+		}
+		r.next++
+	}
+	t := r.next
+	if err := r.register(t, goType, name); err != nil {
+		return 0, err
+	}
+	return t, nil
+}
+
+// RegisterAs 用指定的ComponentType注册组件, 用于手工编号的组件.
+func (r *ComponentRegistry) RegisterAs(t ComponentType, goType reflect.Type, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.register(t, goType, name)
+}
+
+func (r *ComponentRegistry) register(t ComponentType, goType reflect.Type, name string) error {
+	byGo := *r.byGo.Load()
+	if old, ok := byGo[goType]; ok {
+		return fmt.Errorf("%w: %v as %d", ErrComponentRegistered, goType, old)
+	}
+	if old, ok := r.byType[t]; ok {
+		return fmt.Errorf("%w: %d is %v", ErrComponentTypeTaken, t, old.GoType)
+	}
+	if old, ok := r.byName[name]; ok {
+		return fmt.Errorf("%w: %q is %v", ErrComponentNameTaken, name, old.GoType)
+	}
+	info := ComponentInfo{Type: t, Name: name, GoType: goType}
+	r.byType[t] = info
+	r.byName[name] = info
+
+	next := make(map[reflect.Type]ComponentType, len(byGo)+1)
+	for k, v := range byGo {
+		next[k] = v
+	}
+	next[goType] = t
+	r.byGo.Store(&next)
+	return nil
+}
+
+// Info 按ComponentType查找组件信息.
+func (r *ComponentRegistry) Info(t ComponentType) (ComponentInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.byType[t]
+	return info, ok
+}
+
+// Lookup 按名字查找组件信息.
+func (r *ComponentRegistry) Lookup(name string) (ComponentInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.byName[name]
+	return info, ok
+}
+
+// TypeOf 返回Go类型注册的ComponentType.
+func (r *ComponentRegistry) TypeOf(goType reflect.Type) (ComponentType, bool) {
+	t, ok := (*r.byGo.Load())[goType]
+	return t, ok
+}
+
+// Infos 返回所有注册过的组件, 按ComponentType排序.
+func (r *ComponentRegistry) Infos() []ComponentInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := make([]ComponentInfo, 0, len(r.byType))
+	for _, info := range r.byType {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+func (r *ComponentRegistry) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.byType)
+}
+
+// RegisterComponent 在DefaultRegistry里注册组件T并返回分配的ComponentType.
+// 名字默认是Go类型名, 重复注册会panic, 一般在包级变量初始化时调用:
+//
+//	var ComType_pos = entitas.RegisterComponent[*PosCom]()
+func RegisterComponent[T Component](name ...string) ComponentType {
+	goType := reflect.TypeFor[T]()
+	t, err := DefaultRegistry.Register(goType, componentName(goType, name))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// RegisterComponentAs 和RegisterComponent一样, 但是使用指定的ComponentType.
+func RegisterComponentAs[T Component](t ComponentType, name ...string) ComponentType {
+	goType := reflect.TypeFor[T]()
+	if err := DefaultRegistry.RegisterAs(t, goType, componentName(goType, name)); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func componentName(goType reflect.Type, name []string) string {
+	if len(name) > 0 {
+		return name[0]
+	}
+	if goType.Kind() == reflect.Ptr {
+		return goType.Elem().Name()
+	}
+	return goType.Name()
+}
+
+// ComponentOf 嵌入到组件里, 根据DefaultRegistry自动实现Component.Type():
+//
+//	type PosCom struct {
+//		entitas.ComponentOf[*PosCom]
+//		x, y float64
+//	}
+type ComponentOf[T any] struct{}
+
+func (ComponentOf[T]) Type() ComponentType {
+	t, ok := DefaultRegistry.TypeOf(reflect.TypeFor[T]())
+	if !ok {
+		panic(fmt.Sprintf("entitas: component %v is not registered", reflect.TypeFor[T]()))
+	}
+	return t
+}
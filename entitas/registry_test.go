@@ -0,0 +1,109 @@
+package entitas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type registeredComponent struct {
+	ComponentOf[*registeredComponent]
+	value int
+}
+
+var registeredComponentType = RegisterComponentAs[*registeredComponent](100)
+
+func TestComponentRegistry(t *testing.T) {
+	Convey("Given a new registry", t, func() {
+		r := NewComponentRegistry()
+		typeA := reflect.TypeOf(&componentA{})
+		typeB := reflect.TypeOf(&componentB{})
+
+		Convey("It assigns increasing component types", func() {
+			a, err := r.Register(typeA, "A")
+			So(err, ShouldBeNil)
+			b, err := r.Register(typeB, "B")
+			So(err, ShouldBeNil)
+			So(a, ShouldEqual, 0)
+			So(b, ShouldEqual, 1)
+			So(r.Count(), ShouldEqual, 2)
+		})
+
+		Convey("It skips types registered explicitly", func() {
+			So(r.RegisterAs(0, typeA, "A"), ShouldBeNil)
+			b, err := r.Register(typeB, "B")
+			So(err, ShouldBeNil)
+			So(b, ShouldEqual, 1)
+		})
+
+		Convey("It looks components up by type, name and Go type", func() {
+			a, _ := r.Register(typeA, "A")
+			info, ok := r.Info(a)
+			So(ok, ShouldBeTrue)
+			So(info, ShouldResemble, ComponentInfo{Type: a, Name: "A", GoType: typeA})
+			info, ok = r.Lookup("A")
+			So(ok, ShouldBeTrue)
+			So(info.Type, ShouldEqual, a)
+			t, ok := r.TypeOf(typeA)
+			So(ok, ShouldBeTrue)
+			So(t, ShouldEqual, a)
+			_, ok = r.Lookup("B")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("It lists components sorted by type", func() {
+			r.RegisterAs(5, typeB, "B")
+			r.RegisterAs(2, typeA, "A")
+			infos := r.Infos()
+			So(len(infos), ShouldEqual, 2)
+			So(infos[0].Name, ShouldEqual, "A")
+			So(infos[1].Name, ShouldEqual, "B")
+		})
+
+		Convey("It detects duplicate registrations", func() {
+			r.Register(typeA, "A")
+			_, err := r.Register(typeA, "A2")
+			So(errors.Is(err, ErrComponentRegistered), ShouldBeTrue)
+		})
+
+		Convey("It detects colliding component types", func() {
+			r.RegisterAs(3, typeA, "A")
+			err := r.RegisterAs(3, typeB, "B")
+			So(errors.Is(err, ErrComponentTypeTaken), ShouldBeTrue)
+		})
+
+		Convey("It detects colliding names", func() {
+			r.Register(typeA, "A")
+			_, err := r.Register(typeB, "A")
+			So(errors.Is(err, ErrComponentNameTaken), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a component registered in the default registry", t, func() {
+		c := &registeredComponent{value: 1}
+
+		Convey("It derives Type() from the registry", func() {
+			So(c.Type(), ShouldEqual, registeredComponentType)
+			So(TypeOf[*registeredComponent](), ShouldEqual, registeredComponentType)
+		})
+
+		Convey("It is named after its Go type", func() {
+			info, ok := DefaultRegistry.Info(registeredComponentType)
+			So(ok, ShouldBeTrue)
+			So(info.Name, ShouldEqual, "registeredComponent")
+		})
+
+		Convey("It panics when registered twice", func() {
+			So(func() { RegisterComponent[*registeredComponent]() }, ShouldPanic)
+		})
+
+		Convey("It can be stored on an entity", func() {
+			e := NewContext(0).CreateEntity(c)
+			got, ok := Get[*registeredComponent](e)
+			So(ok, ShouldBeTrue)
+			So(got.value, ShouldEqual, 1)
+		})
+	})
+}
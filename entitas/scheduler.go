@@ -0,0 +1,144 @@
+package entitas
+
+import "sync"
+
+// AccessSystem 是可选接口, 声明系统在Execute阶段读写的组件类型.
+// 开启并行执行时, 读写集合不冲突的系统会在不同的goroutine里同时执行;
+// 没有声明读写集合的系统和所有系统冲突, 总是单独执行.
+// 并行执行的系统里不能直接做结构性修改, 需要通过World.Commands()记录.
+type AccessSystem interface {
+	Reads() []ComponentType
+	Writes() []ComponentType
+}
+
+type WorldOption func(*World)
+
+// WithWorkers 设置Execute阶段最多同时执行的系统数量, 默认是1, 即顺序执行.
+func WithWorkers(n int) WorldOption {
+	return func(w *World) {
+		if n < 1 {
+			n = 1
+		}
+		w.workers = n
+	}
+}
+
+type access struct {
+	declared bool
+	reads    map[ComponentType]struct{}
+	writes   map[ComponentType]struct{}
+}
+
+func accessOf(s System) access {
+	as, ok := s.(AccessSystem)
+	if !ok {
+		return access{}
+	}
+	a := access{
+		declared: true,
+		reads:    make(map[ComponentType]struct{}),
+		writes:   make(map[ComponentType]struct{}),
+	}
+	for _, t := range as.Reads() {
+		a.reads[t] = struct{}{}
+	}
+	for _, t := range as.Writes() {
+		a.writes[t] = struct{}{}
+	}
+	return a
+}
+
+// conflicts 判断两个系统能不能同时执行: 一方写的组件另一方读或者写就冲突.
+func (a access) conflicts(b access) bool {
+	if !a.declared || !b.declared {
+		return true
+	}
+	for t := range a.writes {
+		if _, ok := b.reads[t]; ok {
+			return true
+		}
+		if _, ok := b.writes[t]; ok {
+			return true
+		}
+	}
+	for t := range b.writes {
+		if _, ok := a.reads[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// schedule 按依赖图执行任务. 任务j依赖所有和它冲突的更早的任务i,
+// 所以冲突的系统总是按注册顺序执行, 不冲突的系统最多workers个同时执行.
+func schedule(workers int, tasks []func(), accesses []access) {
+	if workers <= 1 || len(tasks) <= 1 {
+		for _, task := range tasks {
+			task()
+		}
+		return
+	}
+
+	pending := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for j := range tasks {
+		for i := 0; i < j; i++ {
+			if accesses[i].conflicts(accesses[j]) {
+				pending[j]++
+				dependents[i] = append(dependents[i], j)
+			}
+		}
+	}
+
+	ready := make(chan int, len(tasks))
+	for j, n := range pending {
+		if n == 0 {
+			ready <- j
+		}
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		remaining = len(tasks)
+		failure   interface{}
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range ready {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							mu.Lock()
+							if failure == nil {
+								failure = r
+							}
+							mu.Unlock()
+						}
+					}()
+					tasks[i]()
+				}()
+
+				mu.Lock()
+				for _, j := range dependents[i] {
+					pending[j]--
+					if pending[j] == 0 {
+						ready <- j
+					}
+				}
+				remaining--
+				if remaining == 0 {
+					close(ready)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failure != nil {
+		panic(failure)
+	}
+}
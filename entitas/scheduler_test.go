@@ -0,0 +1,115 @@
+package entitas
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type accessTestSystem struct {
+	reads  []ComponentType
+	writes []ComponentType
+	run    func()
+}
+
+func (s *accessTestSystem) Reads() []ComponentType   { return s.reads }
+func (s *accessTestSystem) Writes() []ComponentType  { return s.writes }
+func (s *accessTestSystem) Execute(dt time.Duration) { s.run() }
+
+func TestScheduler(t *testing.T) {
+	Convey("Given declared accesses", t, func() {
+		readA := accessOf(&accessTestSystem{reads: []ComponentType{ComponentA}})
+		readAB := accessOf(&accessTestSystem{reads: []ComponentType{ComponentA, ComponentB}})
+		writeA := accessOf(&accessTestSystem{writes: []ComponentType{ComponentA}})
+		writeB := accessOf(&accessTestSystem{reads: []ComponentType{ComponentA}, writes: []ComponentType{ComponentB}})
+		undeclared := accessOf(&executeOnlySystem{})
+
+		Convey("Readers don't conflict", func() {
+			So(readA.conflicts(readAB), ShouldBeFalse)
+		})
+
+		Convey("A writer conflicts with readers and writers of the same type", func() {
+			So(writeA.conflicts(readA), ShouldBeTrue)
+			So(readA.conflicts(writeA), ShouldBeTrue)
+			So(writeA.conflicts(writeA), ShouldBeTrue)
+			So(writeB.conflicts(readAB), ShouldBeTrue)
+		})
+
+		Convey("Writers of different types don't conflict", func() {
+			So(writeB.conflicts(readA), ShouldBeFalse)
+		})
+
+		Convey("Undeclared systems conflict with everything", func() {
+			So(undeclared.conflicts(readA), ShouldBeTrue)
+			So(readA.conflicts(undeclared), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a world with workers", t, func() {
+		w := NewWorld(NewContext(0), WithWorkers(4))
+
+		Convey("It runs non-conflicting systems concurrently", func() {
+			started := make(chan struct{})
+			concurrent := false
+			s1 := &accessTestSystem{reads: []ComponentType{ComponentA}, run: func() {
+				close(started)
+			}}
+			s2 := &accessTestSystem{reads: []ComponentType{ComponentA}, run: func() {
+				select {
+				case <-started:
+					concurrent = true
+				case <-time.After(time.Second):
+				}
+			}}
+			// s2先注册, 只有和s1并行执行时才能等到started.
+			w.AddSystem(s2, s1)
+			w.Tick(time.Millisecond)
+			So(concurrent, ShouldBeTrue)
+		})
+
+		Convey("It runs conflicting systems in registration order", func() {
+			var mu sync.Mutex
+			var order []int
+			for i := 0; i < 8; i++ {
+				i := i
+				w.AddSystem(&accessTestSystem{writes: []ComponentType{ComponentA}, run: func() {
+					mu.Lock()
+					order = append(order, i)
+					mu.Unlock()
+				}})
+			}
+			w.Tick(time.Millisecond)
+			So(order, ShouldResemble, []int{0, 1, 2, 3, 4, 5, 6, 7})
+		})
+
+		Convey("It waits for a conflicting writer before running a reader", func() {
+			var mu sync.Mutex
+			var order []string
+			record := func(s string) {
+				mu.Lock()
+				order = append(order, s)
+				mu.Unlock()
+			}
+			w.AddSystem(
+				&accessTestSystem{writes: []ComponentType{ComponentA}, run: func() {
+					time.Sleep(10 * time.Millisecond)
+					record("writeA")
+				}},
+				&accessTestSystem{reads: []ComponentType{ComponentB}, run: func() { record("readB") }},
+				&accessTestSystem{reads: []ComponentType{ComponentA}, run: func() { record("readA") }},
+			)
+			w.Tick(time.Millisecond)
+			So(order, ShouldResemble, []string{"readB", "writeA", "readA"})
+		})
+
+		Convey("It propagates panics from workers", func() {
+			w.AddSystem(
+				&accessTestSystem{run: func() { panic("boom") }},
+				&accessTestSystem{run: func() {}},
+			)
+			So(func() { w.Tick(time.Millisecond) }, ShouldPanicWith, "boom")
+		})
+	})
+}
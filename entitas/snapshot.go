@@ -0,0 +1,108 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+var (
+	ErrComponentNotRegistered = errors.New("component not registered")
+	ErrUnsupportedContext     = errors.New("context does not support loading")
+	ErrDuplicateEntityID      = errors.New("duplicate entity id")
+)
+
+// entityRecord 是存档里的一个entity, 组件按ComponentType排序.
+type entityRecord struct {
+	ID         EntityID
+	Components []Component
+}
+
+// capture 按ID顺序取出ctx里所有entity的组件, 保证同样的内容总是得到同样的存档.
+func capture(ctx Context) []entityRecord {
+	entities := ctx.Entities()
+	records := make([]entityRecord, len(entities))
+	for i, e := range entities {
+		cs := e.Components()
+		sort.Sort(ComponentsByType(cs))
+		records[i] = entityRecord{ID: e.ID(), Components: cs}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// restore 销毁ctx里所有的entity, 然后按存档里的ID重新创建.
+// group在创建entity时重新计算, 已经拿到的group和observer继续有效.
+// 某个entity没有通过唯一组件或者group的检查时, 恢复成原来的entity, ID, handle和组件再返回错误,
+// 但是entity上的回调和destroy一样会被删掉, group的回调也会收到销毁和重新创建的事件.
+func restore(ctx Context, records []entityRecord) error {
+	p, ok := ctx.(*pool)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrUnsupportedContext, ctx)
+	}
+	ids := make(map[EntityID]struct{}, len(records))
+	for _, r := range records {
+		if _, ok := ids[r.ID]; ok {
+			return fmt.Errorf("%w: %d", ErrDuplicateEntityID, r.ID)
+		}
+		ids[r.ID] = struct{}{}
+	}
+
+	p.lock()
+	defer p.unlock()
+	previous := p.records()
+	generations := make(map[EntityID]uint32, len(previous))
+	for _, r := range previous {
+		generations[r.ID] = p.generations[r.ID]
+	}
+	unused, minID := slices.Clone(p.unused), p.entityMinID
+
+	p.destroyAllEntities()
+	for _, r := range records {
+		if _, err := p.addEntity(p.entityWithID(r.ID), r.Components...); err != nil {
+			p.rollback(previous, generations, unused, minID)
+			return fmt.Errorf("%w on entity %d", err, r.ID)
+		}
+	}
+	return nil
+}
+
+// records 和capture一样取出所有entity的组件, 调用者持有锁.
+func (p *pool) records() []entityRecord {
+	entities := p.entitiesLocked()
+	records := make([]entityRecord, len(entities))
+	for i, e := range entities {
+		records[i] = entityRecord{ID: e.ID(), Components: e.Components()}
+	}
+	return records
+}
+
+// rollback 把restore失败之前的entity按原来的ID和generation加回来, 再恢复unused和下一个ID, 调用者持有锁.
+// 这些entity之前已经在pool里, 所以不再检查.
+func (p *pool) rollback(previous []entityRecord, generations map[EntityID]uint32, unused []contextEntity, minID int) {
+	p.destroyAllEntities()
+	for _, r := range previous {
+		e := p.entityWithID(r.ID)
+		p.generations[r.ID] = generations[r.ID]
+		e.setGeneration(generations[r.ID])
+		e.addComponent(r.Components...)
+		p.registerEntity(e)
+	}
+	p.unused = unused
+	p.entityMinID = minID
+}
+
+// skipField 判断结构体字段是否不用保存. 不占空间的字段没有数据, 其他未导出的字段会丢数据, 返回错误,
+// hint告诉调用者怎么保存这样的组件.
+func skipField(t reflect.Type, i int, hint string) (bool, error) {
+	f := t.Field(i)
+	if f.Type.Size() == 0 {
+		return true, nil
+	}
+	if !f.IsExported() {
+		return false, fmt.Errorf("entitas: cannot encode unexported field %v.%s, %s", t, f.Name, hint)
+	}
+	return false, nil
+}
@@ -0,0 +1,39 @@
+package entitas
+
+import "sync/atomic"
+
+// Subscription 是注册回调时返回的凭证, 传给对应的RemoveCallback可以单独删除这个回调.
+// 所有的Subscription都是唯一的, 传给别的entity或者group的RemoveCallback不会删错回调.
+type Subscription uint64
+
+// InvalidSubscription 不对应任何回调.
+const InvalidSubscription Subscription = 0
+
+var lastSubscription atomic.Uint64
+
+type callbackEntry[F any] struct {
+	sub Subscription
+	fn  F
+}
+
+// callbackList 是一组回调. 删除时复制一份新的slice, 所以正在遍历旧slice的地方不受影响,
+// 回调里可以安全地删除自己.
+type callbackList[F any] []callbackEntry[F]
+
+func (l *callbackList[F]) add(fn F) Subscription {
+	sub := Subscription(lastSubscription.Add(1))
+	*l = append(*l, callbackEntry[F]{sub: sub, fn: fn})
+	return sub
+}
+
+func (l *callbackList[F]) remove(sub Subscription) bool {
+	for i, entry := range *l {
+		if entry.sub == sub {
+			list := make(callbackList[F], 0, len(*l)-1)
+			list = append(list, (*l)[:i]...)
+			*l = append(list, (*l)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package entitas
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubscription(t *testing.T) {
+	Convey("Given an entity with callbacks", t, func() {
+		e := NewEntity(0)
+		var added, replaced int
+		sub := e.AddCallback(ComponentAdded, func(Entity, Component) { added++ })
+		e.AddCallback(ComponentAdded, func(Entity, Component) { added += 10 })
+		replaceSub := e.AddReplaceCallback(func(Entity, Component, Component) { replaced++ })
+
+		Convey("A single callback can be removed", func() {
+			So(e.RemoveCallback(sub), ShouldBeTrue)
+			e.AddComponent(NewComponentA(1))
+			So(added, ShouldEqual, 10)
+		})
+
+		Convey("A replace callback can be removed", func() {
+			So(e.RemoveCallback(replaceSub), ShouldBeTrue)
+			e.AddComponent(NewComponentA(1))
+			e.ReplaceComponent(NewComponentA(2))
+			So(replaced, ShouldEqual, 0)
+			So(e.HasCallbacks(), ShouldBeTrue)
+		})
+
+		Convey("Removing twice or removing a foreign subscription fails", func() {
+			So(e.RemoveCallback(sub), ShouldBeTrue)
+			So(e.RemoveCallback(sub), ShouldBeFalse)
+			So(NewEntity(1).RemoveCallback(replaceSub), ShouldBeFalse)
+			So(e.RemoveCallback(InvalidSubscription), ShouldBeFalse)
+		})
+
+		Convey("A callback can remove itself while being called", func() {
+			var self Subscription
+			calls := 0
+			self = e.AddCallback(ComponentAdded, func(e Entity, c Component) {
+				calls++
+				e.RemoveCallback(self)
+			})
+			e.AddComponent(NewComponentA(1))
+			e.AddComponent(NewComponentB(1))
+			So(calls, ShouldEqual, 1)
+			So(added, ShouldEqual, 22)
+		})
+	})
+
+	Convey("Given a group with callbacks", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		var added, updated int
+		sub := g.AddCallback(EntityAdded, func(Group, Entity) { added++ })
+		updateSub := g.AddUpdateCallback(func(Group, Entity, Component, Component) { updated++ })
+
+		Convey("Callbacks can be removed individually", func() {
+			So(g.RemoveCallback(sub), ShouldBeTrue)
+			So(g.RemoveCallback(updateSub), ShouldBeTrue)
+			e := p.CreateEntity(NewComponentA(1))
+			e.ReplaceComponent(NewComponentA(2))
+			So(added, ShouldEqual, 0)
+			So(updated, ShouldEqual, 0)
+			So(g.RemoveCallback(sub), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given an observer on a group", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA)).(*group)
+		before := len(g.callbacks[EntityAdded]) + len(g.callbacks[EntityRemoved])
+		observer := NewGroupObserver(g, ObserverEntityAddedOrRemoved)
+		p.CreateEntity(NewComponentA(1))
+
+		Convey("Dispose unhooks it from the group", func() {
+			observer.Dispose()
+			So(len(g.callbacks[EntityAdded])+len(g.callbacks[EntityRemoved]), ShouldEqual, before)
+			So(observer.CollectedEntities(), ShouldBeEmpty)
+
+			p.CreateEntity(NewComponentA(2))
+			observer.Activate()
+			p.CreateEntity(NewComponentA(3))
+			So(observer.CollectedEntities(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given an index on a group", t, func() {
+		p := NewContext(0)
+		g := p.Group(AllOf(ComponentA))
+		index := NewEntityIndex(g, valueOfA)
+
+		Convey("Dispose stops updating it", func() {
+			index.Dispose()
+			p.CreateEntity(NewComponentA(1))
+			So(index.Count(1), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,41 @@
+package entitas
+
+import "time"
+
+// System 是所有系统的公共类型. 一个系统实现下面一个或者多个阶段接口,
+// World会在对应的阶段调用它.
+type System interface{}
+
+// InitializeSystem 在World.Initialize时调用一次.
+type InitializeSystem interface {
+	Initialize()
+}
+
+// ExecuteSystem 每次World.Tick时调用.
+type ExecuteSystem interface {
+	Execute(dt time.Duration)
+}
+
+// CleanupSystem 每次World.Tick在所有ExecuteSystem之后调用, 用来清理帧内数据.
+type CleanupSystem interface {
+	Cleanup()
+}
+
+// RenderSystem 每次World.Render时调用, 一般由Loop在每帧的Tick之后调用.
+// alpha在0到1之间, 表示当前时间在上一次和下一次固定步长的Tick之间的位置, 用来插值显示.
+type RenderSystem interface {
+	Render(alpha float64)
+}
+
+// TearDownSystem 在World.TearDown时调用一次.
+type TearDownSystem interface {
+	TearDown()
+}
+
+func isSystem(s System) bool {
+	switch s.(type) {
+	case InitializeSystem, ExecuteSystem, ReactiveSystem, CleanupSystem, RenderSystem, TearDownSystem:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package entitas
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	typeBindingsMu sync.RWMutex
+	typeBindings   = make(map[reflect.Type]ComponentType)
+)
+
+// BindComponent 把Go类型T绑定到组件类型t, 之后Get[T]等函数不再需要传ComponentType.
+func BindComponent[T Component](t ComponentType) {
+	typeBindingsMu.Lock()
+	defer typeBindingsMu.Unlock()
+	typeBindings[reflect.TypeFor[T]()] = t
+}
+
+// TypeOf 返回Go类型T对应的组件类型, 优先使用DefaultRegistry里注册的类型.
+// 没有注册也没有调用过BindComponent时, 用T的零值调用一次Type()并缓存结果,
+// 所以指针类型的组件要求Type()不访问receiver.
+func TypeOf[T Component]() ComponentType {
+	rt := reflect.TypeFor[T]()
+	if t, ok := DefaultRegistry.TypeOf(rt); ok {
+		return t
+	}
+	typeBindingsMu.RLock()
+	t, ok := typeBindings[rt]
+	typeBindingsMu.RUnlock()
+	if ok {
+		return t
+	}
+	t = zeroType[T](rt)
+	BindComponent[T](t)
+	return t
+}
+
+func zeroType[T Component](rt reflect.Type) (t ComponentType) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("entitas: cannot derive component type of %v, call BindComponent first: %v", rt, r))
+		}
+	}()
+	var zero T
+	return zero.Type()
+}
+
+// Get 返回entity上类型为T的组件.
+func Get[T Component](e Entity) (T, bool) {
+	c, ok := e.GetComponent(TypeOf[T]()).(T)
+	return c, ok
+}
+
+// GetMut 和Get一样返回组件, 同时把组件标记为已修改, 用于直接修改组件内部的值.
+func GetMut[T Component](e Entity) (T, bool) {
+	t := TypeOf[T]()
+	c, ok := e.GetComponent(t).(T)
+	if ok {
+		e.MarkChanged(t)
+	}
+	return c, ok
+}
+
+// Has 判断entity是否有类型为T的组件.
+func Has[T Component](e Entity) bool {
+	return e.HasComponent(TypeOf[T]())
+}
+
+// Add 给entity添加组件, 组件已经存在时返回ErrComponentExists.
+func Add[T Component](e Entity, c T) error {
+	return e.AddComponent(c)
+}
+
+// Replace 替换entity上类型为T的组件, 不存在时添加. 错误见Entity.ReplaceComponent.
+func Replace[T Component](e Entity, c T) error {
+	return e.ReplaceComponent(c)
+}
+
+// Remove 删除entity上类型为T的组件.
+func Remove[T Component](e Entity) error {
+	return e.RemoveComponent(TypeOf[T]())
+}
+
+// Each 遍历group里的entity和它们类型为T的组件.
+// 没有这个组件(例如AnyOf或者Where的group), 或者组件不是T的entity会被跳过.
+func Each[T Component](g Group, fn func(e Entity, c T)) {
+	t := TypeOf[T]()
+	for _, e := range g.Entities() {
+		if c, ok := e.GetComponent(t).(T); ok {
+			fn(e, c)
+		}
+	}
+}
+
+// Query 是同时拥有T1和T2两种组件的entity的类型安全视图.
+type Query[T1, T2 Component] struct {
+	group Group
+	t1    ComponentType
+	t2    ComponentType
+}
+
+func NewQuery[T1, T2 Component](ctx Context) *Query[T1, T2] {
+	t1, t2 := TypeOf[T1](), TypeOf[T2]()
+	return &Query[T1, T2]{
+		group: ctx.Group(AllOf(t1, t2)),
+		t1:    t1,
+		t2:    t2,
+	}
+}
+
+func (q *Query[T1, T2]) Group() Group {
+	return q.group
+}
+
+// Each 遍历同时有T1和T2的entity. 组件被替换成了别的Go类型的entity会被跳过.
+func (q *Query[T1, T2]) Each(fn func(e Entity, c1 T1, c2 T2)) {
+	for _, e := range q.group.Entities() {
+		c1, ok1 := e.GetComponent(q.t1).(T1)
+		c2, ok2 := e.GetComponent(q.t2).(T2)
+		if ok1 && ok2 {
+			fn(e, c1, c2)
+		}
+	}
+}
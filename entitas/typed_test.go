@@ -0,0 +1,107 @@
+package entitas
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type unboundComponent struct{ value int }
+
+func (c *unboundComponent) Type() ComponentType { return ComponentType(c.value) }
+
+// otherComponentA 是ComponentA的另一种实现.
+type otherComponentA struct{}
+
+func (c *otherComponentA) Type() ComponentType { return ComponentA }
+
+func TestTypedAccessors(t *testing.T) {
+	Convey("Given a pool with typed components", t, func() {
+		p := NewContext(0)
+		e := p.CreateEntity(NewComponentA(1))
+
+		Convey("It derives the component type from the Go type", func() {
+			So(TypeOf[*componentA](), ShouldEqual, ComponentA)
+			So(TypeOf[*componentB](), ShouldEqual, ComponentB)
+		})
+
+		Convey("It gets a component without casting", func() {
+			a, ok := Get[*componentA](e)
+			So(ok, ShouldBeTrue)
+			So(a.value, ShouldEqual, 1)
+
+			b, ok := Get[*componentB](e)
+			So(ok, ShouldBeFalse)
+			So(b, ShouldBeNil)
+		})
+
+		Convey("It adds, replaces and removes components", func() {
+			So(Has[*componentB](e), ShouldBeFalse)
+			So(Add(e, &componentB{value: 2}), ShouldBeNil)
+			So(Has[*componentB](e), ShouldBeTrue)
+			So(Add(e, &componentB{value: 3}), ShouldEqual, ErrComponentExists)
+
+			Replace(e, &componentA{value: 5})
+			a, _ := Get[*componentA](e)
+			So(a.value, ShouldEqual, 5)
+
+			So(Remove[*componentA](e), ShouldBeNil)
+			So(Has[*componentA](e), ShouldBeFalse)
+		})
+
+		Convey("It iterates a query", func() {
+			e.AddComponent(NewComponentB(1.5))
+			p.CreateEntity(NewComponentA(2))
+			q := NewQuery[*componentA, *componentB](p)
+			count := 0
+			q.Each(func(qe Entity, a *componentA, b *componentB) {
+				So(qe, ShouldEqual, e)
+				So(a.value, ShouldEqual, 1)
+				So(b.value, ShouldEqual, 1.5)
+				count++
+			})
+			So(count, ShouldEqual, 1)
+		})
+
+		Convey("It iterates a group with Each", func() {
+			sum := 0
+			Each(p.Group(AllOf(ComponentA)), func(e Entity, a *componentA) {
+				sum += a.value
+			})
+			So(sum, ShouldEqual, 1)
+		})
+
+		Convey("It skips entities without the component in an AnyOf group", func() {
+			p.CreateEntity(NewComponentB(2))
+			var seen []Entity
+			Each(p.Group(AnyOf(ComponentA, ComponentB)), func(e Entity, a *componentA) {
+				seen = append(seen, e)
+			})
+			So(seen, ShouldResemble, []Entity{e})
+		})
+
+		Convey("It skips components of a different Go type", func() {
+			e.AddComponent(NewComponentB(1))
+			e.ReplaceComponent(&otherComponentA{})
+			count := 0
+			Each(p.Group(AllOf(ComponentA)), func(e Entity, a *componentA) { count++ })
+			NewQuery[*componentA, *componentB](p).Each(func(e Entity, a *componentA, b *componentB) { count++ })
+			So(count, ShouldEqual, 0)
+		})
+
+		Convey("It requires a binding when the type can't be derived", func() {
+			So(func() { TypeOf[*unboundComponent]() }, ShouldPanic)
+			defer unbindComponent[*unboundComponent]()
+			BindComponent[*unboundComponent](ComponentF)
+			So(TypeOf[*unboundComponent](), ShouldEqual, ComponentF)
+		})
+	})
+}
+
+// unbindComponent 删除BindComponent的绑定, 让测试可以重复运行.
+func unbindComponent[T Component]() {
+	typeBindingsMu.Lock()
+	defer typeBindingsMu.Unlock()
+	delete(typeBindings, reflect.TypeFor[T]())
+}
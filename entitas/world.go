@@ -0,0 +1,187 @@
+package entitas
+
+import (
+	"fmt"
+	"time"
+)
+
+type systemEntry struct {
+	system    System
+	enabled   bool
+	collector *collector // 只有ReactiveSystem才有
+	access    access
+}
+
+// World 持有一个Context和按注册顺序排列的系统.
+// 每一帧调用Tick: 先按顺序执行所有ExecuteSystem和ReactiveSystem, 然后回放Commands(), 再执行所有CleanupSystem并再次回放Commands().
+// 需要显示时再调用Render. Loop负责按固定或者可变的步长调用Tick和Render.
+type World struct {
+	context     Context
+	commands    *EntityCommandBuffer
+	systems     []*systemEntry
+	initialized bool
+	workers     int
+}
+
+func NewWorld(ctx Context, opts ...WorldOption) *World {
+	w := &World{
+		context:  ctx,
+		commands: NewEntityCommandBuffer(),
+		workers:  1,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func (w *World) Context() Context {
+	return w.context
+}
+
+// Commands 返回World的命令缓冲, 在Tick的同步点统一回放.
+func (w *World) Commands() *EntityCommandBuffer {
+	return w.commands
+}
+
+// AddSystem 按顺序注册系统. World已经初始化时立即调用新系统的Initialize.
+// 没有实现任何阶段接口的系统会导致panic.
+func (w *World) AddSystem(systems ...System) *World {
+	for _, s := range systems {
+		if !isSystem(s) {
+			panic(fmt.Sprintf("entitas: %T doesn't implement any system interface", s))
+		}
+		entry := &systemEntry{system: s, enabled: true, access: accessOf(s)}
+		w.systems = append(w.systems, entry)
+		if w.initialized {
+			entry.initialize(w.context)
+		}
+	}
+	return w
+}
+
+func (w *World) Systems() []System {
+	systems := make([]System, len(w.systems))
+	for i, entry := range w.systems {
+		systems[i] = entry.system
+	}
+	return systems
+}
+
+// Enable 重新启用被Disable的系统.
+func (w *World) Enable(s System) {
+	if entry := w.entry(s); entry != nil {
+		entry.enabled = true
+		if entry.collector != nil {
+			entry.collector.activate()
+		}
+	}
+}
+
+// Disable 停用系统, 停用的系统不参与Execute和Cleanup阶段.
+// 停用的ReactiveSystem不会收集entity.
+func (w *World) Disable(s System) {
+	if entry := w.entry(s); entry != nil {
+		entry.enabled = false
+		if entry.collector != nil {
+			entry.collector.deactivate()
+		}
+	}
+}
+
+func (w *World) IsEnabled(s System) bool {
+	entry := w.entry(s)
+	return entry != nil && entry.enabled
+}
+
+func (w *World) Initialize() {
+	if w.initialized {
+		return
+	}
+	w.initialized = true
+	for _, entry := range w.systems {
+		entry.initialize(w.context)
+	}
+}
+
+// Tick 执行一帧, 还没有调用过Initialize时先调用它. 返回回放Commands()时遇到的第一个错误.
+func (w *World) Tick(dt time.Duration) error {
+	w.Initialize()
+	var tasks []func()
+	var accesses []access
+	for _, entry := range w.systems {
+		if !entry.enabled {
+			continue
+		}
+		switch s := entry.system.(type) {
+		case ExecuteSystem:
+			tasks = append(tasks, func() { s.Execute(dt) })
+		case ReactiveSystem:
+			tasks = append(tasks, entry.react)
+		default:
+			continue
+		}
+		accesses = append(accesses, entry.access)
+	}
+	schedule(w.workers, tasks, accesses)
+	err := w.commands.Playback(w.context)
+
+	for _, entry := range w.systems {
+		if s, ok := entry.system.(CleanupSystem); ok && entry.enabled {
+			s.Cleanup()
+		}
+	}
+	if cleanupErr := w.commands.Playback(w.context); err == nil {
+		err = cleanupErr
+	}
+	return err
+}
+
+// Render 按注册顺序调用所有启用的RenderSystem. 还没有调用过Initialize时什么都不做,
+// 系统初始化之前没有东西可以显示. Loop在第一帧会先调用Initialize.
+func (w *World) Render(alpha float64) {
+	if !w.initialized {
+		return
+	}
+	for _, entry := range w.systems {
+		if s, ok := entry.system.(RenderSystem); ok && entry.enabled {
+			s.Render(alpha)
+		}
+	}
+}
+
+// TearDown 按注册顺序调用所有TearDownSystem, 并让ReactiveSystem停止收集entity.
+func (w *World) TearDown() {
+	for _, entry := range w.systems {
+		if s, ok := entry.system.(TearDownSystem); ok {
+			s.TearDown()
+		}
+		if entry.collector != nil {
+			entry.collector.dispose()
+			entry.collector = nil
+		}
+	}
+	w.initialized = false
+}
+
+// initialize 调用系统的Initialize, ReactiveSystem在Initialize之后才开始收集entity.
+func (e *systemEntry) initialize(ctx Context) {
+	if s, ok := e.system.(InitializeSystem); ok {
+		s.Initialize()
+	}
+	if s, ok := e.system.(ReactiveSystem); ok && e.collector == nil {
+		e.collector = newCollector(ctx, s.Triggers())
+		if !e.enabled {
+			e.collector.deactivate()
+		}
+	}
+}
+
+func (w *World) entry(s System) *systemEntry {
+	for _, entry := range w.systems {
+		if entry.system == s {
+			return entry
+		}
+	}
+	return nil
+}
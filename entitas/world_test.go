@@ -0,0 +1,114 @@
+package entitas
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingSystem struct {
+	name string
+	log  *[]string
+}
+
+func (s *recordingSystem) Initialize()              { *s.log = append(*s.log, s.name+".init") }
+func (s *recordingSystem) Execute(dt time.Duration) { *s.log = append(*s.log, s.name+".exec") }
+func (s *recordingSystem) Cleanup()                 { *s.log = append(*s.log, s.name+".cleanup") }
+func (s *recordingSystem) TearDown()                { *s.log = append(*s.log, s.name+".teardown") }
+
+type executeOnlySystem struct {
+	dt time.Duration
+}
+
+func (s *executeOnlySystem) Execute(dt time.Duration) { s.dt += dt }
+
+func TestWorld(t *testing.T) {
+	Convey("Given a world with systems", t, func() {
+		var log []string
+		w := NewWorld(NewContext(0))
+		s1 := &recordingSystem{name: "s1", log: &log}
+		s2 := &recordingSystem{name: "s2", log: &log}
+		w.AddSystem(s1, s2)
+
+		Convey("It runs phases in registration order", func() {
+			w.Initialize()
+			w.Tick(time.Second)
+			w.TearDown()
+			So(log, ShouldResemble, []string{
+				"s1.init", "s2.init",
+				"s1.exec", "s2.exec",
+				"s1.cleanup", "s2.cleanup",
+				"s1.teardown", "s2.teardown",
+			})
+		})
+
+		Convey("It initializes on the first tick", func() {
+			w.Tick(time.Second)
+			w.Tick(time.Second)
+			So(log[:2], ShouldResemble, []string{"s1.init", "s2.init"})
+			So(len(log), ShouldEqual, 10)
+		})
+
+		Convey("It initializes systems added after initialization", func() {
+			w.Initialize()
+			s3 := &recordingSystem{name: "s3", log: &log}
+			w.AddSystem(s3)
+			So(log, ShouldResemble, []string{"s1.init", "s2.init", "s3.init"})
+		})
+
+		Convey("It skips disabled systems", func() {
+			w.Initialize()
+			w.Disable(s1)
+			So(w.IsEnabled(s1), ShouldBeFalse)
+			w.Tick(time.Second)
+			So(log, ShouldResemble, []string{"s1.init", "s2.init", "s2.exec", "s2.cleanup"})
+
+			w.Enable(s1)
+			So(w.IsEnabled(s1), ShouldBeTrue)
+			log = log[:0]
+			w.Tick(time.Second)
+			So(log, ShouldResemble, []string{"s1.exec", "s2.exec", "s1.cleanup", "s2.cleanup"})
+		})
+
+		Convey("It renders enabled render systems in order", func() {
+			r1 := &loopSystem{}
+			r2 := &loopSystem{}
+			w.AddSystem(r1, r2)
+			w.Render(0.125)
+			So(log, ShouldBeEmpty)
+			So(r1.alphas, ShouldBeEmpty)
+			w.Initialize()
+			w.Render(0.25)
+			w.Disable(r1)
+			w.Render(0.5)
+			So(log, ShouldResemble, []string{"s1.init", "s2.init"})
+			So(r1.alphas, ShouldResemble, []float64{0.25})
+			So(r2.alphas, ShouldResemble, []float64{0.25, 0.5})
+			So(r1.dts, ShouldBeEmpty)
+		})
+
+		Convey("It passes the delta time to execute systems", func() {
+			s := &executeOnlySystem{}
+			w.AddSystem(s)
+			w.Tick(time.Second)
+			w.Tick(2 * time.Second)
+			So(s.dt, ShouldEqual, 3*time.Second)
+		})
+
+		Convey("It plays back commands after the execute phase", func() {
+			w.Commands().CreateEntity(NewComponentA(1))
+			So(w.Context().Count(), ShouldEqual, 0)
+			So(w.Tick(time.Second), ShouldBeNil)
+			So(w.Context().Count(), ShouldEqual, 1)
+		})
+
+		Convey("It rejects values which are not systems", func() {
+			So(func() { w.AddSystem(42) }, ShouldPanic)
+		})
+
+		Convey("It lists registered systems", func() {
+			So(w.Systems(), ShouldResemble, []System{s1, s2})
+		})
+	})
+}
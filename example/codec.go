@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/yuyistudio/ecs-go/entitas"
+)
+
+// 组件的字段都没有导出, 默认的ReflectCodec没法保存, 所以自己写编码.
+func init() {
+	entitas.SetComponentCodec(ComType_pos, posCodec{})
+	entitas.SetComponentCodec(ComType_renderer, rendererCodec{})
+}
+
+type posCodec struct{}
+
+func (posCodec) Version() uint32 { return 1 }
+
+func (posCodec) Encode(c entitas.Component) ([]byte, error) {
+	pos := c.(*PosCom)
+	data := binary.LittleEndian.AppendUint64(nil, math.Float64bits(pos.x))
+	return binary.LittleEndian.AppendUint64(data, math.Float64bits(pos.y)), nil
+}
+
+func (posCodec) Decode(version uint32, data []byte) (entitas.Component, error) {
+	if len(data) != 16 {
+		return nil, fmt.Errorf("pos: bad data length %d", len(data))
+	}
+	return &PosCom{
+		x: math.Float64frombits(binary.LittleEndian.Uint64(data)),
+		y: math.Float64frombits(binary.LittleEndian.Uint64(data[8:])),
+	}, nil
+}
+
+type rendererCodec struct{}
+
+func (rendererCodec) Version() uint32 { return 1 }
+
+func (rendererCodec) Encode(c entitas.Component) ([]byte, error) {
+	return binary.AppendVarint(nil, c.(*RendererCom).screen), nil
+}
+
+func (rendererCodec) Decode(version uint32, data []byte) (entitas.Component, error) {
+	screen, n := binary.Varint(data)
+	if n <= 0 || n != len(data) {
+		return nil, fmt.Errorf("renderer: bad data")
+	}
+	return &RendererCom{screen: screen}, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/yuyistudio/ecs-go/entitas"
+)
+
+func TestExampleCodecs(t *testing.T) {
+	Convey("Given a context with the example's components", t, func() {
+		context := entitas.NewContext(888)
+		context.CreateEntity(&PosCom{x: 1, y: 2})
+		context.CreateEntity(&RendererCom{screen: 888}, &PosCom{x: 3, y: -4.5})
+
+		Convey("A binary round trip keeps the unexported fields", func() {
+			var buf bytes.Buffer
+			So(entitas.SaveBinary(context, &buf), ShouldBeNil)
+			loaded := entitas.NewContext(0)
+			So(entitas.LoadBinary(loaded, &buf), ShouldBeNil)
+
+			entities := loaded.Entities()
+			So(len(entities), ShouldEqual, 2)
+			So(entities[0].ID(), ShouldEqual, 888)
+			So(entities[0].GetComponent(ComType_pos), ShouldResemble, &PosCom{x: 1, y: 2})
+			So(entities[1].GetComponent(ComType_pos), ShouldResemble, &PosCom{x: 3, y: -4.5})
+			So(entities[1].GetComponent(ComType_renderer), ShouldResemble, &RendererCom{screen: 888})
+		})
+	})
+}
@@ -2,710 +2,605 @@ package main
 
 import "github.com/yuyistudio/ecs-go/entitas"
 
-
-const (
-	ComType_pos entitas.ComponentType = iota
-	ComType_renderer
-	ComType_com0
-	ComType_com1
-	ComType_com2
-	ComType_com3
-	ComType_com4
-	ComType_com5
-	ComType_com6
-	ComType_com7
-	ComType_com8
-	ComType_com9
-	ComType_com10
-	ComType_com11
-	ComType_com12
-	ComType_com13
-	ComType_com14
-	ComType_com15
-	ComType_com16
-	ComType_com17
-	ComType_com18
-	ComType_com19
-	ComType_com20
-	ComType_com21
-	ComType_com22
-	ComType_com23
-	ComType_com24
-	ComType_com25
-	ComType_com26
-	ComType_com27
-	ComType_com28
-	ComType_com29
-	ComType_com30
-	ComType_com31
-	ComType_com32
-	ComType_com33
-	ComType_com34
-	ComType_com35
-	ComType_com36
-	ComType_com37
-	ComType_com38
-	ComType_com39
-	ComType_com40
-	ComType_com41
-	ComType_com42
-	ComType_com43
-	ComType_com44
-	ComType_com45
-	ComType_com46
-	ComType_com47
-	ComType_com48
-	ComType_com49
-	ComType_com50
-	ComType_com51
-	ComType_com52
-	ComType_com53
-	ComType_com54
-	ComType_com55
-	ComType_com56
-	ComType_com57
-	ComType_com58
-	ComType_com59
-	ComType_com60
-	ComType_com61
-	ComType_com62
-	ComType_com63
-	ComType_com64
-	ComType_com65
-	ComType_com66
-	ComType_com67
-	ComType_com68
-	ComType_com69
-	ComType_com70
-	ComType_com71
-	ComType_com72
-	ComType_com73
-	ComType_com74
-	ComType_com75
-	ComType_com76
-	ComType_com77
-	ComType_com78
-	ComType_com79
-	ComType_com80
-	ComType_com81
-	ComType_com82
-	ComType_com83
-	ComType_com84
-	ComType_com85
-	ComType_com86
-	ComType_com87
-	ComType_com88
-	ComType_com89
-	ComType_com90
-	ComType_com91
-	ComType_com92
-	ComType_com93
-	ComType_com94
-	ComType_com95
-	ComType_com96
-	ComType_com97
-	ComType_com98
-	ComType_com99
-	ComTypeCount
+var (
+	ComType_com0  = entitas.RegisterComponent[*Com0]("com0")
+	ComType_com1  = entitas.RegisterComponent[*Com1]("com1")
+	ComType_com2  = entitas.RegisterComponent[*Com2]("com2")
+	ComType_com3  = entitas.RegisterComponent[*Com3]("com3")
+	ComType_com4  = entitas.RegisterComponent[*Com4]("com4")
+	ComType_com5  = entitas.RegisterComponent[*Com5]("com5")
+	ComType_com6  = entitas.RegisterComponent[*Com6]("com6")
+	ComType_com7  = entitas.RegisterComponent[*Com7]("com7")
+	ComType_com8  = entitas.RegisterComponent[*Com8]("com8")
+	ComType_com9  = entitas.RegisterComponent[*Com9]("com9")
+	ComType_com10 = entitas.RegisterComponent[*Com10]("com10")
+	ComType_com11 = entitas.RegisterComponent[*Com11]("com11")
+	ComType_com12 = entitas.RegisterComponent[*Com12]("com12")
+	ComType_com13 = entitas.RegisterComponent[*Com13]("com13")
+	ComType_com14 = entitas.RegisterComponent[*Com14]("com14")
+	ComType_com15 = entitas.RegisterComponent[*Com15]("com15")
+	ComType_com16 = entitas.RegisterComponent[*Com16]("com16")
+	ComType_com17 = entitas.RegisterComponent[*Com17]("com17")
+	ComType_com18 = entitas.RegisterComponent[*Com18]("com18")
+	ComType_com19 = entitas.RegisterComponent[*Com19]("com19")
+	ComType_com20 = entitas.RegisterComponent[*Com20]("com20")
+	ComType_com21 = entitas.RegisterComponent[*Com21]("com21")
+	ComType_com22 = entitas.RegisterComponent[*Com22]("com22")
+	ComType_com23 = entitas.RegisterComponent[*Com23]("com23")
+	ComType_com24 = entitas.RegisterComponent[*Com24]("com24")
+	ComType_com25 = entitas.RegisterComponent[*Com25]("com25")
+	ComType_com26 = entitas.RegisterComponent[*Com26]("com26")
+	ComType_com27 = entitas.RegisterComponent[*Com27]("com27")
+	ComType_com28 = entitas.RegisterComponent[*Com28]("com28")
+	ComType_com29 = entitas.RegisterComponent[*Com29]("com29")
+	ComType_com30 = entitas.RegisterComponent[*Com30]("com30")
+	ComType_com31 = entitas.RegisterComponent[*Com31]("com31")
+	ComType_com32 = entitas.RegisterComponent[*Com32]("com32")
+	ComType_com33 = entitas.RegisterComponent[*Com33]("com33")
+	ComType_com34 = entitas.RegisterComponent[*Com34]("com34")
+	ComType_com35 = entitas.RegisterComponent[*Com35]("com35")
+	ComType_com36 = entitas.RegisterComponent[*Com36]("com36")
+	ComType_com37 = entitas.RegisterComponent[*Com37]("com37")
+	ComType_com38 = entitas.RegisterComponent[*Com38]("com38")
+	ComType_com39 = entitas.RegisterComponent[*Com39]("com39")
+	ComType_com40 = entitas.RegisterComponent[*Com40]("com40")
+	ComType_com41 = entitas.RegisterComponent[*Com41]("com41")
+	ComType_com42 = entitas.RegisterComponent[*Com42]("com42")
+	ComType_com43 = entitas.RegisterComponent[*Com43]("com43")
+	ComType_com44 = entitas.RegisterComponent[*Com44]("com44")
+	ComType_com45 = entitas.RegisterComponent[*Com45]("com45")
+	ComType_com46 = entitas.RegisterComponent[*Com46]("com46")
+	ComType_com47 = entitas.RegisterComponent[*Com47]("com47")
+	ComType_com48 = entitas.RegisterComponent[*Com48]("com48")
+	ComType_com49 = entitas.RegisterComponent[*Com49]("com49")
+	ComType_com50 = entitas.RegisterComponent[*Com50]("com50")
+	ComType_com51 = entitas.RegisterComponent[*Com51]("com51")
+	ComType_com52 = entitas.RegisterComponent[*Com52]("com52")
+	ComType_com53 = entitas.RegisterComponent[*Com53]("com53")
+	ComType_com54 = entitas.RegisterComponent[*Com54]("com54")
+	ComType_com55 = entitas.RegisterComponent[*Com55]("com55")
+	ComType_com56 = entitas.RegisterComponent[*Com56]("com56")
+	ComType_com57 = entitas.RegisterComponent[*Com57]("com57")
+	ComType_com58 = entitas.RegisterComponent[*Com58]("com58")
+	ComType_com59 = entitas.RegisterComponent[*Com59]("com59")
+	ComType_com60 = entitas.RegisterComponent[*Com60]("com60")
+	ComType_com61 = entitas.RegisterComponent[*Com61]("com61")
+	ComType_com62 = entitas.RegisterComponent[*Com62]("com62")
+	ComType_com63 = entitas.RegisterComponent[*Com63]("com63")
+	ComType_com64 = entitas.RegisterComponent[*Com64]("com64")
+	ComType_com65 = entitas.RegisterComponent[*Com65]("com65")
+	ComType_com66 = entitas.RegisterComponent[*Com66]("com66")
+	ComType_com67 = entitas.RegisterComponent[*Com67]("com67")
+	ComType_com68 = entitas.RegisterComponent[*Com68]("com68")
+	ComType_com69 = entitas.RegisterComponent[*Com69]("com69")
+	ComType_com70 = entitas.RegisterComponent[*Com70]("com70")
+	ComType_com71 = entitas.RegisterComponent[*Com71]("com71")
+	ComType_com72 = entitas.RegisterComponent[*Com72]("com72")
+	ComType_com73 = entitas.RegisterComponent[*Com73]("com73")
+	ComType_com74 = entitas.RegisterComponent[*Com74]("com74")
+	ComType_com75 = entitas.RegisterComponent[*Com75]("com75")
+	ComType_com76 = entitas.RegisterComponent[*Com76]("com76")
+	ComType_com77 = entitas.RegisterComponent[*Com77]("com77")
+	ComType_com78 = entitas.RegisterComponent[*Com78]("com78")
+	ComType_com79 = entitas.RegisterComponent[*Com79]("com79")
+	ComType_com80 = entitas.RegisterComponent[*Com80]("com80")
+	ComType_com81 = entitas.RegisterComponent[*Com81]("com81")
+	ComType_com82 = entitas.RegisterComponent[*Com82]("com82")
+	ComType_com83 = entitas.RegisterComponent[*Com83]("com83")
+	ComType_com84 = entitas.RegisterComponent[*Com84]("com84")
+	ComType_com85 = entitas.RegisterComponent[*Com85]("com85")
+	ComType_com86 = entitas.RegisterComponent[*Com86]("com86")
+	ComType_com87 = entitas.RegisterComponent[*Com87]("com87")
+	ComType_com88 = entitas.RegisterComponent[*Com88]("com88")
+	ComType_com89 = entitas.RegisterComponent[*Com89]("com89")
+	ComType_com90 = entitas.RegisterComponent[*Com90]("com90")
+	ComType_com91 = entitas.RegisterComponent[*Com91]("com91")
+	ComType_com92 = entitas.RegisterComponent[*Com92]("com92")
+	ComType_com93 = entitas.RegisterComponent[*Com93]("com93")
+	ComType_com94 = entitas.RegisterComponent[*Com94]("com94")
+	ComType_com95 = entitas.RegisterComponent[*Com95]("com95")
+	ComType_com96 = entitas.RegisterComponent[*Com96]("com96")
+	ComType_com97 = entitas.RegisterComponent[*Com97]("com97")
+	ComType_com98 = entitas.RegisterComponent[*Com98]("com98")
+	ComType_com99 = entitas.RegisterComponent[*Com99]("com99")
 )
 
 type Com0 struct {
+	entitas.ComponentOf[*Com0]
 	x float64
 }
-func (p *Com0) Type() entitas.ComponentType {
-	return ComType_com0
-}
+
 type Com1 struct {
+	entitas.ComponentOf[*Com1]
 	x float64
 }
-func (p *Com1) Type() entitas.ComponentType {
-	return ComType_com1
-}
+
 type Com2 struct {
+	entitas.ComponentOf[*Com2]
 	x float64
 }
-func (p *Com2) Type() entitas.ComponentType {
-	return ComType_com2
-}
+
 type Com3 struct {
+	entitas.ComponentOf[*Com3]
 	x float64
 }
-func (p *Com3) Type() entitas.ComponentType {
-	return ComType_com3
-}
+
 type Com4 struct {
+	entitas.ComponentOf[*Com4]
 	x float64
 }
-func (p *Com4) Type() entitas.ComponentType {
-	return ComType_com4
-}
+
 type Com5 struct {
+	entitas.ComponentOf[*Com5]
 	x float64
 }
-func (p *Com5) Type() entitas.ComponentType {
-	return ComType_com5
-}
+
 type Com6 struct {
+	entitas.ComponentOf[*Com6]
 	x float64
 }
-func (p *Com6) Type() entitas.ComponentType {
-	return ComType_com6
-}
+
 type Com7 struct {
+	entitas.ComponentOf[*Com7]
 	x float64
 }
-func (p *Com7) Type() entitas.ComponentType {
-	return ComType_com7
-}
+
 type Com8 struct {
+	entitas.ComponentOf[*Com8]
 	x float64
 }
-func (p *Com8) Type() entitas.ComponentType {
-	return ComType_com8
-}
+
 type Com9 struct {
+	entitas.ComponentOf[*Com9]
 	x float64
 }
-func (p *Com9) Type() entitas.ComponentType {
-	return ComType_com9
-}
+
 type Com10 struct {
+	entitas.ComponentOf[*Com10]
 	x float64
 }
-func (p *Com10) Type() entitas.ComponentType {
-	return ComType_com10
-}
+
 type Com11 struct {
+	entitas.ComponentOf[*Com11]
 	x float64
 }
-func (p *Com11) Type() entitas.ComponentType {
-	return ComType_com11
-}
+
 type Com12 struct {
+	entitas.ComponentOf[*Com12]
 	x float64
 }
-func (p *Com12) Type() entitas.ComponentType {
-	return ComType_com12
-}
+
 type Com13 struct {
+	entitas.ComponentOf[*Com13]
 	x float64
 }
-func (p *Com13) Type() entitas.ComponentType {
-	return ComType_com13
-}
+
 type Com14 struct {
+	entitas.ComponentOf[*Com14]
 	x float64
 }
-func (p *Com14) Type() entitas.ComponentType {
-	return ComType_com14
-}
+
 type Com15 struct {
+	entitas.ComponentOf[*Com15]
 	x float64
 }
-func (p *Com15) Type() entitas.ComponentType {
-	return ComType_com15
-}
+
 type Com16 struct {
+	entitas.ComponentOf[*Com16]
 	x float64
 }
-func (p *Com16) Type() entitas.ComponentType {
-	return ComType_com16
-}
+
 type Com17 struct {
+	entitas.ComponentOf[*Com17]
 	x float64
 }
-func (p *Com17) Type() entitas.ComponentType {
-	return ComType_com17
-}
+
 type Com18 struct {
+	entitas.ComponentOf[*Com18]
 	x float64
 }
-func (p *Com18) Type() entitas.ComponentType {
-	return ComType_com18
-}
+
 type Com19 struct {
+	entitas.ComponentOf[*Com19]
 	x float64
 }
-func (p *Com19) Type() entitas.ComponentType {
-	return ComType_com19
-}
+
 type Com20 struct {
+	entitas.ComponentOf[*Com20]
 	x float64
 }
-func (p *Com20) Type() entitas.ComponentType {
-	return ComType_com20
-}
+
 type Com21 struct {
+	entitas.ComponentOf[*Com21]
 	x float64
 }
-func (p *Com21) Type() entitas.ComponentType {
-	return ComType_com21
-}
+
 type Com22 struct {
+	entitas.ComponentOf[*Com22]
 	x float64
 }
-func (p *Com22) Type() entitas.ComponentType {
-	return ComType_com22
-}
+
 type Com23 struct {
+	entitas.ComponentOf[*Com23]
 	x float64
 }
-func (p *Com23) Type() entitas.ComponentType {
-	return ComType_com23
-}
+
 type Com24 struct {
+	entitas.ComponentOf[*Com24]
 	x float64
 }
-func (p *Com24) Type() entitas.ComponentType {
-	return ComType_com24
-}
+
 type Com25 struct {
+	entitas.ComponentOf[*Com25]
 	x float64
 }
-func (p *Com25) Type() entitas.ComponentType {
-	return ComType_com25
-}
+
 type Com26 struct {
+	entitas.ComponentOf[*Com26]
 	x float64
 }
-func (p *Com26) Type() entitas.ComponentType {
-	return ComType_com26
-}
+
 type Com27 struct {
+	entitas.ComponentOf[*Com27]
 	x float64
 }
-func (p *Com27) Type() entitas.ComponentType {
-	return ComType_com27
-}
+
 type Com28 struct {
+	entitas.ComponentOf[*Com28]
 	x float64
 }
-func (p *Com28) Type() entitas.ComponentType {
-	return ComType_com28
-}
+
 type Com29 struct {
+	entitas.ComponentOf[*Com29]
 	x float64
 }
-func (p *Com29) Type() entitas.ComponentType {
-	return ComType_com29
-}
+
 type Com30 struct {
+	entitas.ComponentOf[*Com30]
 	x float64
 }
-func (p *Com30) Type() entitas.ComponentType {
-	return ComType_com30
-}
+
 type Com31 struct {
+	entitas.ComponentOf[*Com31]
 	x float64
 }
-func (p *Com31) Type() entitas.ComponentType {
-	return ComType_com31
-}
+
 type Com32 struct {
+	entitas.ComponentOf[*Com32]
 	x float64
 }
-func (p *Com32) Type() entitas.ComponentType {
-	return ComType_com32
-}
+
 type Com33 struct {
+	entitas.ComponentOf[*Com33]
 	x float64
 }
-func (p *Com33) Type() entitas.ComponentType {
-	return ComType_com33
-}
+
 type Com34 struct {
+	entitas.ComponentOf[*Com34]
 	x float64
 }
-func (p *Com34) Type() entitas.ComponentType {
-	return ComType_com34
-}
+
 type Com35 struct {
+	entitas.ComponentOf[*Com35]
 	x float64
 }
-func (p *Com35) Type() entitas.ComponentType {
-	return ComType_com35
-}
+
 type Com36 struct {
+	entitas.ComponentOf[*Com36]
 	x float64
 }
-func (p *Com36) Type() entitas.ComponentType {
-	return ComType_com36
-}
+
 type Com37 struct {
+	entitas.ComponentOf[*Com37]
 	x float64
 }
-func (p *Com37) Type() entitas.ComponentType {
-	return ComType_com37
-}
+
 type Com38 struct {
+	entitas.ComponentOf[*Com38]
 	x float64
 }
-func (p *Com38) Type() entitas.ComponentType {
-	return ComType_com38
-}
+
 type Com39 struct {
+	entitas.ComponentOf[*Com39]
 	x float64
 }
-func (p *Com39) Type() entitas.ComponentType {
-	return ComType_com39
-}
+
 type Com40 struct {
+	entitas.ComponentOf[*Com40]
 	x float64
 }
-func (p *Com40) Type() entitas.ComponentType {
-	return ComType_com40
-}
+
 type Com41 struct {
+	entitas.ComponentOf[*Com41]
 	x float64
 }
-func (p *Com41) Type() entitas.ComponentType {
-	return ComType_com41
-}
+
 type Com42 struct {
+	entitas.ComponentOf[*Com42]
 	x float64
 }
-func (p *Com42) Type() entitas.ComponentType {
-	return ComType_com42
-}
+
 type Com43 struct {
+	entitas.ComponentOf[*Com43]
 	x float64
 }
-func (p *Com43) Type() entitas.ComponentType {
-	return ComType_com43
-}
+
 type Com44 struct {
+	entitas.ComponentOf[*Com44]
 	x float64
 }
-func (p *Com44) Type() entitas.ComponentType {
-	return ComType_com44
-}
+
 type Com45 struct {
+	entitas.ComponentOf[*Com45]
 	x float64
 }
-func (p *Com45) Type() entitas.ComponentType {
-	return ComType_com45
-}
+
 type Com46 struct {
+	entitas.ComponentOf[*Com46]
 	x float64
 }
-func (p *Com46) Type() entitas.ComponentType {
-	return ComType_com46
-}
+
 type Com47 struct {
+	entitas.ComponentOf[*Com47]
 	x float64
 }
-func (p *Com47) Type() entitas.ComponentType {
-	return ComType_com47
-}
+
 type Com48 struct {
+	entitas.ComponentOf[*Com48]
 	x float64
 }
-func (p *Com48) Type() entitas.ComponentType {
-	return ComType_com48
-}
+
 type Com49 struct {
+	entitas.ComponentOf[*Com49]
 	x float64
 }
-func (p *Com49) Type() entitas.ComponentType {
-	return ComType_com49
-}
+
 type Com50 struct {
+	entitas.ComponentOf[*Com50]
 	x float64
 }
-func (p *Com50) Type() entitas.ComponentType {
-	return ComType_com50
-}
+
 type Com51 struct {
+	entitas.ComponentOf[*Com51]
 	x float64
 }
-func (p *Com51) Type() entitas.ComponentType {
-	return ComType_com51
-}
+
 type Com52 struct {
+	entitas.ComponentOf[*Com52]
 	x float64
 }
-func (p *Com52) Type() entitas.ComponentType {
-	return ComType_com52
-}
+
 type Com53 struct {
+	entitas.ComponentOf[*Com53]
 	x float64
 }
-func (p *Com53) Type() entitas.ComponentType {
-	return ComType_com53
-}
+
 type Com54 struct {
+	entitas.ComponentOf[*Com54]
 	x float64
 }
-func (p *Com54) Type() entitas.ComponentType {
-	return ComType_com54
-}
+
 type Com55 struct {
+	entitas.ComponentOf[*Com55]
 	x float64
 }
-func (p *Com55) Type() entitas.ComponentType {
-	return ComType_com55
-}
+
 type Com56 struct {
+	entitas.ComponentOf[*Com56]
 	x float64
 }
-func (p *Com56) Type() entitas.ComponentType {
-	return ComType_com56
-}
+
 type Com57 struct {
+	entitas.ComponentOf[*Com57]
 	x float64
 }
-func (p *Com57) Type() entitas.ComponentType {
-	return ComType_com57
-}
+
 type Com58 struct {
+	entitas.ComponentOf[*Com58]
 	x float64
 }
-func (p *Com58) Type() entitas.ComponentType {
-	return ComType_com58
-}
+
 type Com59 struct {
+	entitas.ComponentOf[*Com59]
 	x float64
 }
-func (p *Com59) Type() entitas.ComponentType {
-	return ComType_com59
-}
+
 type Com60 struct {
+	entitas.ComponentOf[*Com60]
 	x float64
 }
-func (p *Com60) Type() entitas.ComponentType {
-	return ComType_com60
-}
+
 type Com61 struct {
+	entitas.ComponentOf[*Com61]
 	x float64
 }
-func (p *Com61) Type() entitas.ComponentType {
-	return ComType_com61
-}
+
 type Com62 struct {
+	entitas.ComponentOf[*Com62]
 	x float64
 }
-func (p *Com62) Type() entitas.ComponentType {
-	return ComType_com62
-}
+
 type Com63 struct {
+	entitas.ComponentOf[*Com63]
 	x float64
 }
-func (p *Com63) Type() entitas.ComponentType {
-	return ComType_com63
-}
+
 type Com64 struct {
+	entitas.ComponentOf[*Com64]
 	x float64
 }
-func (p *Com64) Type() entitas.ComponentType {
-	return ComType_com64
-}
+
 type Com65 struct {
+	entitas.ComponentOf[*Com65]
 	x float64
 }
-func (p *Com65) Type() entitas.ComponentType {
-	return ComType_com65
-}
+
 type Com66 struct {
+	entitas.ComponentOf[*Com66]
 	x float64
 }
-func (p *Com66) Type() entitas.ComponentType {
-	return ComType_com66
-}
+
 type Com67 struct {
+	entitas.ComponentOf[*Com67]
 	x float64
 }
-func (p *Com67) Type() entitas.ComponentType {
-	return ComType_com67
-}
+
 type Com68 struct {
+	entitas.ComponentOf[*Com68]
 	x float64
 }
-func (p *Com68) Type() entitas.ComponentType {
-	return ComType_com68
-}
+
 type Com69 struct {
+	entitas.ComponentOf[*Com69]
 	x float64
 }
-func (p *Com69) Type() entitas.ComponentType {
-	return ComType_com69
-}
+
 type Com70 struct {
+	entitas.ComponentOf[*Com70]
 	x float64
 }
-func (p *Com70) Type() entitas.ComponentType {
-	return ComType_com70
-}
+
 type Com71 struct {
+	entitas.ComponentOf[*Com71]
 	x float64
 }
-func (p *Com71) Type() entitas.ComponentType {
-	return ComType_com71
-}
+
 type Com72 struct {
+	entitas.ComponentOf[*Com72]
 	x float64
 }
-func (p *Com72) Type() entitas.ComponentType {
-	return ComType_com72
-}
+
 type Com73 struct {
+	entitas.ComponentOf[*Com73]
 	x float64
 }
-func (p *Com73) Type() entitas.ComponentType {
-	return ComType_com73
-}
+
 type Com74 struct {
+	entitas.ComponentOf[*Com74]
 	x float64
 }
-func (p *Com74) Type() entitas.ComponentType {
-	return ComType_com74
-}
+
 type Com75 struct {
+	entitas.ComponentOf[*Com75]
 	x float64
 }
-func (p *Com75) Type() entitas.ComponentType {
-	return ComType_com75
-}
+
 type Com76 struct {
+	entitas.ComponentOf[*Com76]
 	x float64
 }
-func (p *Com76) Type() entitas.ComponentType {
-	return ComType_com76
-}
+
 type Com77 struct {
+	entitas.ComponentOf[*Com77]
 	x float64
 }
-func (p *Com77) Type() entitas.ComponentType {
-	return ComType_com77
-}
+
 type Com78 struct {
+	entitas.ComponentOf[*Com78]
 	x float64
 }
-func (p *Com78) Type() entitas.ComponentType {
-	return ComType_com78
-}
+
 type Com79 struct {
+	entitas.ComponentOf[*Com79]
 	x float64
 }
-func (p *Com79) Type() entitas.ComponentType {
-	return ComType_com79
-}
+
 type Com80 struct {
+	entitas.ComponentOf[*Com80]
 	x float64
 }
-func (p *Com80) Type() entitas.ComponentType {
-	return ComType_com80
-}
+
 type Com81 struct {
+	entitas.ComponentOf[*Com81]
 	x float64
 }
-func (p *Com81) Type() entitas.ComponentType {
-	return ComType_com81
-}
+
 type Com82 struct {
+	entitas.ComponentOf[*Com82]
 	x float64
 }
-func (p *Com82) Type() entitas.ComponentType {
-	return ComType_com82
-}
+
 type Com83 struct {
+	entitas.ComponentOf[*Com83]
 	x float64
 }
-func (p *Com83) Type() entitas.ComponentType {
-	return ComType_com83
-}
+
 type Com84 struct {
+	entitas.ComponentOf[*Com84]
 	x float64
 }
-func (p *Com84) Type() entitas.ComponentType {
-	return ComType_com84
-}
+
 type Com85 struct {
+	entitas.ComponentOf[*Com85]
 	x float64
 }
-func (p *Com85) Type() entitas.ComponentType {
-	return ComType_com85
-}
+
 type Com86 struct {
+	entitas.ComponentOf[*Com86]
 	x float64
 }
-func (p *Com86) Type() entitas.ComponentType {
-	return ComType_com86
-}
+
 type Com87 struct {
+	entitas.ComponentOf[*Com87]
 	x float64
 }
-func (p *Com87) Type() entitas.ComponentType {
-	return ComType_com87
-}
+
 type Com88 struct {
+	entitas.ComponentOf[*Com88]
 	x float64
 }
-func (p *Com88) Type() entitas.ComponentType {
-	return ComType_com88
-}
+
 type Com89 struct {
+	entitas.ComponentOf[*Com89]
 	x float64
 }
-func (p *Com89) Type() entitas.ComponentType {
-	return ComType_com89
-}
+
 type Com90 struct {
+	entitas.ComponentOf[*Com90]
 	x float64
 }
-func (p *Com90) Type() entitas.ComponentType {
-	return ComType_com90
-}
+
 type Com91 struct {
+	entitas.ComponentOf[*Com91]
 	x float64
 }
-func (p *Com91) Type() entitas.ComponentType {
-	return ComType_com91
-}
+
 type Com92 struct {
+	entitas.ComponentOf[*Com92]
 	x float64
 }
-func (p *Com92) Type() entitas.ComponentType {
-	return ComType_com92
-}
+
 type Com93 struct {
+	entitas.ComponentOf[*Com93]
 	x float64
 }
-func (p *Com93) Type() entitas.ComponentType {
-	return ComType_com93
-}
+
 type Com94 struct {
+	entitas.ComponentOf[*Com94]
 	x float64
 }
-func (p *Com94) Type() entitas.ComponentType {
-	return ComType_com94
-}
+
 type Com95 struct {
+	entitas.ComponentOf[*Com95]
 	x float64
 }
-func (p *Com95) Type() entitas.ComponentType {
-	return ComType_com95
-}
+
 type Com96 struct {
+	entitas.ComponentOf[*Com96]
 	x float64
 }
-func (p *Com96) Type() entitas.ComponentType {
-	return ComType_com96
-}
+
 type Com97 struct {
+	entitas.ComponentOf[*Com97]
 	x float64
 }
-func (p *Com97) Type() entitas.ComponentType {
-	return ComType_com97
-}
+
 type Com98 struct {
+	entitas.ComponentOf[*Com98]
 	x float64
 }
-func (p *Com98) Type() entitas.ComponentType {
-	return ComType_com98
-}
+
 type Com99 struct {
+	entitas.ComponentOf[*Com99]
 	x float64
 }
-func (p *Com99) Type() entitas.ComponentType {
-	return ComType_com99
-}
@@ -5,25 +5,27 @@ import (
 	"fmt"
 	"time"
 	"math/rand"
+	gocontext "context"
+	"os"
+	"os/signal"
+)
+
+var (
+	ComType_pos      = entitas.RegisterComponent[*PosCom]("pos")
+	ComType_renderer = entitas.RegisterComponent[*RendererCom]("renderer")
 )
 
 type PosCom struct {
+	entitas.ComponentOf[*PosCom]
 	x float64
 	y float64
 }
 
-func (p *PosCom) Type() entitas.ComponentType {
-	return ComType_pos
-}
-
 type RendererCom struct {
+	entitas.ComponentOf[*RendererCom]
 	screen int64
 }
 
-func (p *RendererCom) Type() entitas.ComponentType {
-	return ComType_renderer
-}
-
 type PosMatcher struct {
 	hash     entitas.MatcherHash
 }
@@ -54,59 +56,29 @@ func (m *PosMatcher) String() string {
 	return fmt.Sprintf("PosMatcher(%v)", m.Hash())
 }
 
-type System interface {
-	OnInit()
-	OnUpdate()
-	OnCleanup()
-}
-
-type World struct {
-	context entitas.Context
-	systems []System
-}
-
-func (w *World) OnInit() {
-	for _, system := range w.systems {
-		system.OnInit()
-	}
-}
-
-func (w *World) OnUpdate() {
-	for _, system := range w.systems {
-		system.OnUpdate()
-	}
-	for _, system := range w.systems {
-		system.OnCleanup()
-	}
-}
-
-func (w *World) AddSystem(system System) {
-	w.systems = append(w.systems, system)
-}
-
 type MovementSystem struct {
 	g entitas.Group
 	context entitas.Context
 }
 
-func NewMovementSystem(context entitas.Context) System {
+func NewMovementSystem(context entitas.Context) *MovementSystem {
 	m := new(MovementSystem)
 	m.context = context
 	return m
 }
-func (m *MovementSystem) OnInit() {
+func (m *MovementSystem) Initialize() {
 	posMatcher := NewPosMatch()
 	m.g = m.context.Group(posMatcher)
 }
-func (m *MovementSystem) OnUpdate() {
+func (m *MovementSystem) Execute(dt time.Duration) {
 	for _, entity := range m.g.Entities() {
-		posCom := entity.GetComponent(ComType_pos).(*PosCom)
+		posCom, _ := entitas.GetMut[*PosCom](entity)
 		posCom.y += 1
 		fmt.Printf("entity[%d].pos.y = %v\n", entity.ID(), posCom.y)
 	}
 }
 
-func (m *MovementSystem) OnCleanup() {
+func (m *MovementSystem) Cleanup() {
 	fmt.Printf("cleaning frame data\n")
 }
 
@@ -116,21 +88,22 @@ func TestWorld(){
 	context.CreateEntity(&PosCom{x:1, y:2})
 	context.CreateEntity(&RendererCom{screen:888}, &PosCom{x:3, y:4})
 
-	world := new(World)
-	world.context = context
-
+	world := entitas.NewWorld(context)
 	world.AddSystem(NewMovementSystem(context))
-	world.OnInit()
-
-	for {
-		// frame
-		world.OnUpdate()
-		time.Sleep(500 * time.Millisecond)
+	world.Initialize()
+
+	ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt)
+	defer stop()
+	const frame = 500 * time.Millisecond
+	loop := entitas.NewLoop(world, entitas.WithFixedStep(frame))
+	if err := loop.Run(ctx); err != nil && err != gocontext.Canceled {
+		fmt.Println(err)
 	}
 }
 
 func testGetCom() {
-	fmt.Printf("com count %d\n", ComTypeCount)
+	comTypeCount := entitas.DefaultRegistry.Count()
+	fmt.Printf("com count %d\n", comTypeCount)
 	context := entitas.NewContext(888)
 	e1 := context.CreateEntity(&PosCom{x:1, y:2})
 	/*
@@ -266,7 +239,7 @@ func testGetCom() {
 		rand.Seed(seed)
 		st := time.Now()
 		for i := 0; i < loopCount; i++ {
-			e.DictGetComponent(entitas.ComponentType(rand.Intn(int(ComTypeCount))))
+			e.DictGetComponent(entitas.ComponentType(rand.Intn(comTypeCount)))
 		}
 		et := time.Now()
 		fmt.Printf("hash-index: %v\n", et.Sub(st))
@@ -275,7 +248,7 @@ func testGetCom() {
 		rand.Seed(seed)
 		st := time.Now()
 		for i := 0; i < loopCount; i++ {
-			e.BinarySearchComponent(entitas.ComponentType(rand.Intn(int(ComTypeCount))))
+			e.BinarySearchComponent(entitas.ComponentType(rand.Intn(comTypeCount)))
 		}
 		et := time.Now()
 		fmt.Printf("binary-search: %v\n", et.Sub(st))
@@ -284,7 +257,7 @@ func testGetCom() {
 		rand.Seed(seed)
 		st := time.Now()
 		for i := 0; i < loopCount; i++ {
-			e.GetComponent(entitas.ComponentType(rand.Intn(int(ComTypeCount))))
+			e.GetComponent(entitas.ComponentType(rand.Intn(comTypeCount)))
 		}
 		et := time.Now()
 		fmt.Printf("get-com: %v\n", et.Sub(st))